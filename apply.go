@@ -3,8 +3,11 @@ package golembic
 // ApplyConfig provides configurable fields for "up" commands that will apply
 // migrations.
 type ApplyConfig struct {
-	VerifyHistory bool
-	Revision      string
+	VerifyHistory     bool
+	Revision          string
+	DryRun            bool
+	SingleTransaction bool
+	Retry             RetryPolicy
 }
 
 // NewApplyConfig creates a new `ApplyConfig` and applies options.
@@ -35,3 +38,40 @@ func OptApplyRevision(revision string) ApplyOption {
 		return nil
 	}
 }
+
+// OptApplyDryRun sets `DryRun` on an `ApplyConfig`.
+func OptApplyDryRun(dryRun bool) ApplyOption {
+	return func(ac *ApplyConfig) error {
+		ac.DryRun = dryRun
+		return nil
+	}
+}
+
+// OptApplySingleTransaction sets `SingleTransaction` on an `ApplyConfig`. When
+// set, every pending migration (along with the corresponding rows in the
+// migrations metadata table) is applied in one transaction, so a failure
+// leaves the database unchanged. This requires a provider that satisfies
+// `TransactionalDDLProvider` and is rejected if any pending migration uses
+// `UpConn`.
+func OptApplySingleTransaction(single bool) ApplyOption {
+	return func(ac *ApplyConfig) error {
+		ac.SingleTransaction = single
+		return nil
+	}
+}
+
+// OptApplyRetry sets `Retry` on an `ApplyConfig`. This determines how a
+// transactional migration that fails with a retryable error (e.g. a lock
+// timeout, see `RetryableErrorProvider`) is retried. With
+// `OptApplySingleTransaction()`, the whole transaction is retried.
+func OptApplyRetry(policy RetryPolicy) ApplyOption {
+	return func(ac *ApplyConfig) error {
+		err := policy.validate()
+		if err != nil {
+			return err
+		}
+
+		ac.Retry = policy
+		return nil
+	}
+}
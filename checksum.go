@@ -0,0 +1,14 @@
+package golembic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SQLChecksum computes a checksum (a hex encoded SHA-256 digest) of a SQL
+// statement. This is used to detect when the SQL for a migration has been
+// modified after the migration was applied.
+func SQLChecksum(statement string) string {
+	digest := sha256.Sum256([]byte(statement))
+	return hex.EncodeToString(digest[:])
+}
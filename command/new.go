@@ -0,0 +1,335 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dhermes/golembic"
+)
+
+const (
+	// DefaultFilenamePattern is the default pattern used by the `new`
+	// subcommand to name a new `.sql` file.
+	DefaultFilenamePattern = "NNNN_<revision>_<slug>.sql"
+)
+
+var (
+	numberPlaceholder = regexp.MustCompile(`N+`)
+	numberPrefix      = regexp.MustCompile(`^([0-9]+)_`)
+	nonSlugCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func newSubCommand(manager *golembic.Manager, sqlDirectory *string, out *outputOptions) *cobra.Command {
+	description := ""
+	filenamePattern := DefaultFilenamePattern
+	milestone := false
+	transactional := true
+	goStub := false
+
+	short := "Create a new migration at the head of the registered sequence"
+	long := strings.Join([]string{
+		short + ".",
+		"",
+		"If the sequence has diverged into multiple heads, the new migration",
+		"will be a merge migration with every head as a parent.",
+		"",
+		"This generates a random revision and writes a new `.sql` file into the",
+		"directory specified by `--sql-directory`. The filename pattern may use",
+		"NNNN (the next number, zero padded to the number of N characters),",
+		"<slug> (derived from the description) and <revision>. This does not",
+		"make any connection to the database. The default pattern is the one",
+		"expected by `golembic.LoadSequenceFromDir()`.",
+	}, "\n")
+	cmd := &cobra.Command{
+		Use:   "new",
+		Short: short,
+		Long:  long,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nm, err := createMigrationFile(
+				manager.Sequence, *sqlDirectory, filenamePattern, description, milestone, transactional,
+			)
+			if err != nil {
+				return out.emit(cmd, nil, err)
+			}
+
+			stub := ""
+			if goStub {
+				stub = nm.goStub()
+			}
+			if out.isJSON() {
+				return out.emit(cmd, newNewMigrationJSON(nm, stub), nil)
+			}
+
+			w := cmd.OutOrStdout()
+			fmt.Fprintf(w, "Created %s for revision %s\n", nm.Path, nm.Revision)
+			if stub != "" {
+				fmt.Fprint(w, stub)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&description,
+		"description",
+		"",
+		"The description of the new migration",
+	)
+	cobra.MarkFlagRequired(cmd.Flags(), "description")
+	cmd.Flags().StringVar(
+		&filenamePattern,
+		"filename-pattern",
+		filenamePattern,
+		"The pattern used to name the new \".sql\" file, e.g. \"NNNN_<slug>.sql\" for files registered in Go",
+	)
+	cmd.Flags().BoolVar(
+		&milestone,
+		"milestone",
+		false,
+		"If set, the new migration will be a milestone",
+	)
+	cmd.Flags().BoolVar(
+		&transactional,
+		"transactional",
+		true,
+		"Flag indicating that the new migration should run in a transaction",
+	)
+	cmd.Flags().BoolVar(
+		&goStub,
+		"go-stub",
+		false,
+		"If set, also display Go code that registers the new migration",
+	)
+
+	return cmd
+}
+
+// newMigration describes a `.sql` file created by the `new` subcommand.
+type newMigration struct {
+	Path          string
+	Filename      string
+	Revision      string
+	Previous      string
+	Merges        []string
+	Description   string
+	Milestone     bool
+	Transactional bool
+}
+
+// createMigrationFile creates a new `.sql` file in `sqlDirectory` for a
+// migration that follows the current head of `sequence`; if there are
+// multiple heads, the new migration merges all of them. This will fail
+// rather than overwrite an existing file.
+func createMigrationFile(sequence *golembic.Migrations, sqlDirectory, filenamePattern, description string, milestone, transactional bool) (*newMigration, error) {
+	if sqlDirectory == "" {
+		return nil, errors.New("The new command requires --sql-directory to be set")
+	}
+	if sequence == nil {
+		return nil, errors.New("Cannot create a new migration without a registered sequence")
+	}
+	slug := slugify(description)
+	if slug == "" {
+		return nil, fmt.Errorf("Cannot create a slug from description; description: %q", description)
+	}
+
+	number, err := nextFileNumber(sqlDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := golembic.NewRevision()
+	if err != nil {
+		return nil, err
+	}
+
+	heads := sequence.Heads()
+	nm := &newMigration{
+		Revision:      revision,
+		Previous:      heads[len(heads)-1].Revision,
+		Description:   description,
+		Milestone:     milestone,
+		Transactional: transactional,
+	}
+	for _, head := range heads[:len(heads)-1] {
+		nm.Merges = append(nm.Merges, head.Revision)
+	}
+	nm.Filename = renderFilename(filenamePattern, number, slug, revision)
+	if nm.Filename != filepath.Base(nm.Filename) {
+		return nil, fmt.Errorf("Filename pattern must not contain a directory; filename: %q", nm.Filename)
+	}
+	nm.Path = filepath.Join(sqlDirectory, nm.Filename)
+
+	// NOTE: `os.O_EXCL` ensures that an existing file is not overwritten.
+	f, err := os.OpenFile(nm.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = f.WriteString(nm.header())
+	closeErr := f.Close()
+	err = maybeWrap(err, closeErr, "failed to close file")
+	if err != nil {
+		return nil, err
+	}
+
+	err = nm.checkRoundTrip(sqlDirectory)
+	if err != nil {
+		removeErr := os.Remove(nm.Path)
+		return nil, maybeWrap(err, removeErr, "failed to remove file")
+	}
+
+	return nm, nil
+}
+
+// checkRoundTrip makes sure that the new `.sql` file is loaded by
+// `golembic.LoadSequenceFromDir()` with the same revision that is displayed.
+// A filename that does not follow the naming convention of the loader (e.g.
+// for a file that is registered in Go code via `golembic.OptUpFromFS()`) is
+// not checked.
+func (nm *newMigration) checkRoundTrip(sqlDirectory string) error {
+	migration, err := golembic.LoadMigrationFromFS(os.DirFS(sqlDirectory), nm.Filename)
+	if errors.Is(err, golembic.ErrMalformedFilename) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if migration.Revision != nm.Revision {
+		return fmt.Errorf(
+			"New migration would be loaded with a different revision; filename: %q, revision: %q, loaded: %q",
+			nm.Filename, nm.Revision, migration.Revision,
+		)
+	}
+
+	return nil
+}
+
+// nextFileNumber determines the number for a new `.sql` file, i.e. one more
+// than the largest `NNNN_` prefix among the existing `.sql` files.
+func nextFileNumber(sqlDirectory string) (int, error) {
+	entries, err := os.ReadDir(sqlDirectory)
+	if err != nil {
+		return 0, err
+	}
+
+	largest := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := numberPrefix.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		number, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0, err
+		}
+		if number > largest {
+			largest = number
+		}
+	}
+
+	return largest + 1, nil
+}
+
+// renderFilename replaces the placeholders in a filename pattern.
+func renderFilename(pattern string, number int, slug, revision string) string {
+	filename := numberPlaceholder.ReplaceAllStringFunc(pattern, func(ns string) string {
+		return fmt.Sprintf("%0*d", len(ns), number)
+	})
+	filename = strings.ReplaceAll(filename, "<slug>", slug)
+	return strings.ReplaceAll(filename, "<revision>", revision)
+}
+
+// slugify converts a description into a lowercase slug that only contains
+// letters, digits and underscores.
+func slugify(description string) string {
+	slug := nonSlugCharacters.ReplaceAllString(strings.ToLower(description), "_")
+	return strings.Trim(slug, "_")
+}
+
+// header returns the header comment block for the new `.sql` file. The
+// `description`, `milestone`, `transactional`, `revision`, `previous` and
+// `merges` keys are understood by `golembic.LoadSequenceFromDir()`.
+func (nm *newMigration) header() string {
+	lines := []string{
+		"-- description: " + nm.Description,
+		"-- milestone: " + strconv.FormatBool(nm.Milestone),
+		"-- transactional: " + strconv.FormatBool(nm.Transactional),
+		"-- revision: " + nm.Revision,
+		"-- previous: " + nm.Previous,
+	}
+	if len(nm.Merges) > 0 {
+		lines = append(lines, "-- merges: "+strings.Join(nm.Merges, ", "))
+	}
+	lines = append(lines, "")
+	return strings.Join(lines, "\n")
+}
+
+// goStub returns Go code that can be added to a call to
+// `Migrations.RegisterManyOpt()` to register the new migration.
+func (nm *newMigration) goStub() string {
+	lines := []string{
+		"[]golembic.MigrationOption{",
+		fmt.Sprintf("\tgolembic.OptPrevious(%q),", nm.Previous),
+		fmt.Sprintf("\tgolembic.OptRevision(%q),", nm.Revision),
+		fmt.Sprintf("\tgolembic.OptDescription(%q),", nm.Description),
+	}
+	if len(nm.Merges) > 0 {
+		merges := make([]string, len(nm.Merges))
+		for i, merge := range nm.Merges {
+			merges[i] = strconv.Quote(merge)
+		}
+		lines = append(lines, fmt.Sprintf("\tgolembic.OptMerges(%s),", strings.Join(merges, ", ")))
+	}
+	if nm.Milestone {
+		lines = append(lines, "\tgolembic.OptMilestone(true),")
+	}
+	if nm.Transactional {
+		lines = append(lines, fmt.Sprintf("\tgolembic.OptUpFromFS(fsys, %q),", nm.Filename))
+	} else {
+		lines = append(lines, fmt.Sprintf("\tgolembic.OptUpConnFromFS(fsys, %q),", nm.Filename))
+	}
+	lines = append(lines, "},", "")
+	return strings.Join(lines, "\n")
+}
+
+// newMigrationJSON is the result for the `new` subcommand.
+type newMigrationJSON struct {
+	Path          string   `json:"path"`
+	Revision      string   `json:"revision"`
+	Previous      string   `json:"previous"`
+	Merges        []string `json:"merges"`
+	Description   string   `json:"description"`
+	Milestone     bool     `json:"milestone"`
+	Transactional bool     `json:"transactional"`
+	GoStub        *string  `json:"go_stub"`
+}
+
+func newNewMigrationJSON(nm *newMigration, stub string) newMigrationJSON {
+	nmj := newMigrationJSON{
+		Path:          nm.Path,
+		Revision:      nm.Revision,
+		Previous:      nm.Previous,
+		Merges:        []string{},
+		Description:   nm.Description,
+		Milestone:     nm.Milestone,
+		Transactional: nm.Transactional,
+	}
+	nmj.Merges = append(nmj.Merges, nm.Merges...)
+	if stub != "" {
+		nmj.GoStub = &stub
+	}
+	return nmj
+}
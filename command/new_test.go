@@ -0,0 +1,230 @@
+package command
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dhermes/golembic"
+)
+
+func TestRenderFilename(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name    string
+		Pattern string
+		Number  int
+		Want    string
+	}{
+		{Name: "default", Pattern: DefaultFilenamePattern, Number: 7, Want: "0007_abc123_create_users.sql"},
+		{Name: "number wider than padding", Pattern: DefaultFilenamePattern, Number: 12345, Want: "12345_abc123_create_users.sql"},
+		{Name: "single digit padding", Pattern: "N-<slug>.sql", Number: 3, Want: "3-create_users.sql"},
+		{Name: "no number", Pattern: "<revision>.sql", Number: 3, Want: "abc123.sql"},
+		{Name: "repeated placeholders", Pattern: "NN_<slug>_<slug>_NNN.sql", Number: 4, Want: "04_create_users_create_users_004.sql"},
+		{Name: "no placeholders", Pattern: "migration.sql", Number: 1, Want: "migration.sql"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			got := renderFilename(tc.Pattern, tc.Number, "create_users", "abc123")
+			if got != tc.Want {
+				t.Fatalf("renderFilename(%q, %d) = %q, want %q", tc.Pattern, tc.Number, got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Description string
+		Want        string
+	}{
+		{Description: "Create users table", Want: "create_users_table"},
+		{Description: "  Add index on users(email)!  ", Want: "add_index_on_users_email"},
+		{Description: "Drop `legacy` -- columns", Want: "drop_legacy_columns"},
+		{Description: "v2_schema", Want: "v2_schema"},
+		{Description: "Ünïcode names", Want: "n_code_names"},
+		{Description: "!!!", Want: ""},
+		{Description: "", Want: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			t.Parallel()
+
+			got := slugify(tc.Description)
+			if got != tc.Want {
+				t.Fatalf("slugify(%q) = %q, want %q", tc.Description, got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestNextFileNumber(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name  string
+		Files []string
+		Want  int
+	}{
+		{Name: "empty", Files: nil, Want: 1},
+		{Name: "sequential", Files: []string{"0001_a_x.sql", "0002_b_y.sql"}, Want: 3},
+		{Name: "gap", Files: []string{"0001_a_x.sql", "0009_b_y.sql"}, Want: 10},
+		{Name: "ignored files", Files: []string{"0001_a_x.sql", "0005_b_y.txt", "notes.sql", "README.md"}, Want: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for _, name := range tc.Files {
+				err := os.WriteFile(filepath.Join(dir, name), nil, 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := os.Mkdir(filepath.Join(dir, "0100_c_dir.sql"), 0o755)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := nextFileNumber(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Want {
+				t.Fatalf("nextFileNumber() = %d, want %d", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestCreateMigrationFile(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name     string
+		Existing map[string]string
+		// Want is the parents (previous, then merges) of the new migration.
+		Want []string
+	}{
+		{
+			Name: "single head",
+			Existing: map[string]string{
+				"0001_a_root.sql":  "SELECT 1;\n",
+				"0002_b_child.sql": "SELECT 2;\n",
+			},
+			Want: []string{"b"},
+		},
+		{
+			Name: "multiple heads",
+			Existing: map[string]string{
+				"0001_a_root.sql":  "SELECT 1;\n",
+				"0002_b_left.sql":  "SELECT 2;\n",
+				"0003_c_right.sql": "-- previous: a\nSELECT 3;\n",
+			},
+			Want: []string{"c", "b"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for name, contents := range tc.Existing {
+				err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			sequence, err := golembic.LoadSequenceFromDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			nm, err := createMigrationFile(sequence, dir, DefaultFilenamePattern, "Add users", false, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The new file must be loadable as the new (single) head.
+			loaded, err := golembic.LoadSequenceFromDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			heads := loaded.Heads()
+			if len(heads) != 1 || heads[0].Revision != nm.Revision {
+				t.Fatalf("heads = %v, want [%s]", heads, nm.Revision)
+			}
+			if got := heads[0].Parents(); !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("parents = %v, want %v", got, tc.Want)
+			}
+			if heads[0].Description != "Add users" {
+				t.Fatalf("description = %q, want %q", heads[0].Description, "Add users")
+			}
+
+			// A file that already exists is never overwritten.
+			_, err = createMigrationFile(sequence, dir, "<slug>.sql", "Add users", false, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = createMigrationFile(sequence, dir, "<slug>.sql", "Add users", false, true)
+			if !errors.Is(err, fs.ErrExist) {
+				t.Fatalf("error = %v, want %v", err, fs.ErrExist)
+			}
+		})
+	}
+}
+
+func TestCreateMigrationFileInvalid(t *testing.T) {
+	t.Parallel()
+
+	root, err := golembic.NewMigration(golembic.OptRevision("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequence, err := golembic.NewSequence(*root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Sequence    *golembic.Migrations
+		NoDirectory bool
+		Pattern     string
+		Description string
+	}{
+		{Name: "no directory", Sequence: sequence, NoDirectory: true, Pattern: DefaultFilenamePattern, Description: "Add users"},
+		{Name: "no sequence", Pattern: DefaultFilenamePattern, Description: "Add users"},
+		{Name: "empty slug", Sequence: sequence, Pattern: DefaultFilenamePattern, Description: "!!!"},
+		{Name: "directory in pattern", Sequence: sequence, Pattern: "nested/<slug>.sql", Description: "Add users"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if tc.NoDirectory {
+				dir = ""
+			}
+
+			_, err := createMigrationFile(tc.Sequence, dir, tc.Pattern, tc.Description, false, true)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dhermes/golembic"
+)
+
+const (
+	// outputText is the output format that displays human readable text.
+	outputText = "text"
+	// outputJSON is the output format that emits a single JSON document on
+	// STDOUT for each subcommand.
+	outputJSON = "json"
+	// outputSchemaVersion is the version of the JSON documents emitted in
+	// JSON output mode. It will be incremented if the schema changes in a
+	// way that is not backwards compatible.
+	outputSchemaVersion = 1
+)
+
+// NOTE: Ensure that
+//   - `stderrPrintf` satisfies `golembic.PrintfReceiver`.
+var (
+	_ golembic.PrintfReceiver = (*stderrPrintf)(nil)
+)
+
+// stderrPrintf implements `golembic.PrintfReceiver` and just prints to STDERR.
+// This is used in JSON output mode so that STDOUT only contains JSON.
+type stderrPrintf struct{}
+
+func (sp *stderrPrintf) Printf(format string, a ...interface{}) (n int, err error) {
+	return fmt.Fprintf(os.Stderr, format+"\n", a...)
+}
+
+// outputOptions holds the value of the `--output` flag shared by all
+// subcommands.
+type outputOptions struct {
+	Format string
+}
+
+func (oo *outputOptions) validate() error {
+	if oo.Format == outputText || oo.Format == outputJSON {
+		return nil
+	}
+
+	return fmt.Errorf("Invalid output %q, must be one of %q or %q", oo.Format, outputText, outputJSON)
+}
+
+func (oo *outputOptions) isJSON() bool {
+	return oo.Format == outputJSON
+}
+
+// emit writes the JSON document for a subcommand to STDOUT. In text output
+// mode, this does nothing. In either case, `err` is passed through so that
+// the command still fails.
+func (oo *outputOptions) emit(cmd *cobra.Command, result interface{}, err error) error {
+	if !oo.isJSON() {
+		return err
+	}
+
+	d := document{
+		SchemaVersion: outputSchemaVersion,
+		Command:       cmd.Name(),
+		Result:        result,
+	}
+	if err != nil {
+		message := err.Error()
+		d.Error = &message
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	encodeErr := encoder.Encode(d)
+	return maybeWrap(err, encodeErr, "failed to write JSON output")
+}
+
+// emitOnPreRunError makes sure a JSON document is still emitted when a
+// subcommand fails before it is run, e.g. if the migrations cannot be
+// registered or a connection pool cannot be created.
+func (oo *outputOptions) emitOnPreRunError(cmd *cobra.Command) {
+	preRunE := cmd.PersistentPreRunE
+	if preRunE == nil {
+		return
+	}
+
+	cmd.PersistentPreRunE = func(leaf *cobra.Command, args []string) error {
+		err := preRunE(leaf, args)
+		if err == nil {
+			return nil
+		}
+
+		return oo.emit(leaf, nil, err)
+	}
+}
+
+// document is the JSON document emitted by every subcommand in JSON output
+// mode. The shape of `Result` depends on the subcommand and will be `null`
+// if the subcommand failed before producing a result.
+type document struct {
+	SchemaVersion int         `json:"schema_version"`
+	Command       string      `json:"command"`
+	Result        interface{} `json:"result"`
+	Error         *string     `json:"error"`
+}
+
+// describeJSON is the result for the `describe` subcommand.
+type describeJSON struct {
+	Migrations []migrationJSON `json:"migrations"`
+}
+
+// migrationJSON is the JSON representation of a registered
+// `golembic.Migration`.
+type migrationJSON struct {
+	Revision      string   `json:"revision"`
+	Previous      *string  `json:"previous"`
+	Merges        []string `json:"merges"`
+	Description   string   `json:"description"`
+	Milestone     bool     `json:"milestone"`
+	Baseline      bool     `json:"baseline"`
+	Transactional bool     `json:"transactional"`
+}
+
+func newDescribeJSON(migrations []golembic.Migration) describeJSON {
+	dj := describeJSON{Migrations: []migrationJSON{}}
+	for _, migration := range migrations {
+		mj := migrationJSON{
+			Revision:      migration.Revision,
+			Merges:        []string{},
+			Description:   migration.Description,
+			Milestone:     migration.Milestone,
+			Baseline:      migration.Baseline,
+			Transactional: migration.Transactional(),
+		}
+		mj.Merges = append(mj.Merges, migration.Merges...)
+		if migration.Previous != "" {
+			previous := migration.Previous
+			mj.Previous = &previous
+		}
+		dj.Migrations = append(dj.Migrations, mj)
+	}
+	return dj
+}
+
+// headsJSON is the result for the `heads` subcommand.
+type headsJSON struct {
+	Heads []migrationJSON `json:"heads"`
+}
+
+func newHeadsJSON(heads []golembic.Migration) headsJSON {
+	return headsJSON{Heads: newDescribeJSON(heads).Migrations}
+}
+
+// statusJSON is the result for the `status` and `verify` subcommands.
+type statusJSON struct {
+	Migrations []statusEntryJSON `json:"migrations"`
+}
+
+// statusEntryJSON is the JSON representation of a `golembic.StatusEntry`.
+type statusEntryJSON struct {
+	Revision      string     `json:"revision"`
+	Description   string     `json:"description"`
+	Milestone     bool       `json:"milestone"`
+	Baseline      bool       `json:"baseline"`
+	Transactional bool       `json:"transactional"`
+	Applied       bool       `json:"applied"`
+	Dirty         bool       `json:"dirty"`
+	AppliedAt     *time.Time `json:"applied_at"`
+	applyMetadataJSON
+}
+
+func newStatusJSON(entries []golembic.StatusEntry) *statusJSON {
+	if entries == nil {
+		return nil
+	}
+
+	sj := &statusJSON{Migrations: []statusEntryJSON{}}
+	for _, entry := range entries {
+		sej := statusEntryJSON{
+			Revision:      entry.Revision,
+			Description:   entry.Description,
+			Milestone:     entry.Milestone,
+			Baseline:      entry.Baseline,
+			Transactional: entry.Transactional,
+			Applied:       entry.Applied,
+			Dirty:         entry.Dirty,
+		}
+		if entry.Applied {
+			appliedAt := entry.AppliedAt
+			sej.AppliedAt = &appliedAt
+			sej.applyMetadataJSON = newApplyMetadataJSON(entry.ApplyMetadata)
+		}
+		sj.Migrations = append(sj.Migrations, sej)
+	}
+	return sj
+}
+
+// versionJSON is the result for the `version` subcommand. `Version` will be
+// `null` if no migrations have been run.
+type versionJSON struct {
+	Version *appliedMigrationJSON `json:"version"`
+}
+
+// appliedMigrationJSON is the JSON representation of a `golembic.Migration`
+// that was read from the migrations metadata table.
+type appliedMigrationJSON struct {
+	Revision    string    `json:"revision"`
+	Description string    `json:"description"`
+	Milestone   bool      `json:"milestone"`
+	AppliedAt   time.Time `json:"applied_at"`
+	applyMetadataJSON
+}
+
+func newVersionJSON(migration *golembic.Migration) versionJSON {
+	vj := versionJSON{}
+	if migration != nil {
+		vj.Version = &appliedMigrationJSON{
+			Revision:    migration.Revision,
+			Description: migration.Description,
+			Milestone:   migration.Milestone,
+			AppliedAt:   migration.CreatedAt(),
+		}
+		vj.Version.applyMetadataJSON = newApplyMetadataJSON(migration.ApplyMetadata())
+	}
+	return vj
+}
+
+// applyMetadataJSON is the JSON representation of a `golembic.ApplyMetadata`;
+// it is embedded in the JSON representation of an applied migration. Strings
+// that were not recorded will be `null`.
+type applyMetadataJSON struct {
+	AppliedBy          *string `json:"applied_by"`
+	Hostname           *string `json:"hostname"`
+	DurationMS         int64   `json:"duration_ms"`
+	ApplicationVersion *string `json:"application_version"`
+}
+
+func newApplyMetadataJSON(am golembic.ApplyMetadata) applyMetadataJSON {
+	amj := applyMetadataJSON{
+		AppliedBy:          optionalString(am.AppliedBy),
+		Hostname:           optionalString(am.Hostname),
+		DurationMS:         am.Duration.Milliseconds(),
+		ApplicationVersion: optionalString(am.ApplicationVersion),
+	}
+	return amj
+}
+
+// optionalString converts the empty string to `nil` so it will be `null`
+// in JSON output.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// stampJSON is the result for the `stamp` subcommand.
+type stampJSON struct {
+	Revision string `json:"revision"`
+}
+
+// repairJSON is the result for the `repair` subcommand.
+type repairJSON struct {
+	Revision     string `json:"revision,omitempty"`
+	Action       string `json:"action,omitempty"`
+	ReleasedLock bool   `json:"released_lock,omitempty"`
+}
+
+// metadataTableJSON is the result for the `upgrade-metadata-table`
+// subcommand.
+type metadataTableJSON struct {
+	Table   string `json:"table"`
+	Version int    `json:"version"`
+}
+
+// applyReportJSON is the JSON representation of a `golembic.ApplyReport`;
+// it is the result for the `up`, `up-one` and `up-to` subcommands.
+type applyReportJSON struct {
+	DryRun          bool                  `json:"dry_run"`
+	Started         time.Time             `json:"started"`
+	Finished        time.Time             `json:"finished"`
+	DurationSeconds float64               `json:"duration_seconds"`
+	Migrations      []migrationResultJSON `json:"migrations"`
+}
+
+// migrationResultJSON is the JSON representation of a
+// `golembic.MigrationResult`.
+type migrationResultJSON struct {
+	Revision        string     `json:"revision"`
+	Description     string     `json:"description"`
+	Milestone       bool       `json:"milestone"`
+	Status          string     `json:"status"`
+	Started         *time.Time `json:"started"`
+	Finished        *time.Time `json:"finished"`
+	DurationSeconds *float64   `json:"duration_seconds"`
+	Error           *string    `json:"error"`
+}
+
+func newApplyReportJSON(report *golembic.ApplyReport) *applyReportJSON {
+	if report == nil {
+		return nil
+	}
+
+	arj := &applyReportJSON{
+		DryRun:          report.DryRun,
+		Started:         report.Started,
+		Finished:        report.Finished,
+		DurationSeconds: report.Duration.Seconds(),
+		Migrations:      []migrationResultJSON{},
+	}
+	for _, result := range report.Migrations {
+		mrj := migrationResultJSON{
+			Revision:    result.Revision,
+			Description: result.Description,
+			Milestone:   result.Milestone,
+			Status:      string(result.Status),
+		}
+		if !result.Started.IsZero() {
+			started := result.Started
+			finished := result.Finished
+			durationSeconds := result.Duration.Seconds()
+			mrj.Started = &started
+			mrj.Finished = &finished
+			mrj.DurationSeconds = &durationSeconds
+		}
+		if result.Err != nil {
+			message := result.Err.Error()
+			mrj.Error = &message
+		}
+		arj.Migrations = append(arj.Migrations, mrj)
+	}
+	return arj
+}
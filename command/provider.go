@@ -3,25 +3,33 @@ package command
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/dhermes/golembic"
 )
 
-func registerProviderSubcommands(cmd *cobra.Command, manager *golembic.Manager) {
+func registerProviderSubcommands(cmd *cobra.Command, manager *golembic.Manager, out *outputOptions) {
 	cmd.AddCommand(
-		describeSubCommand(manager),
-		upSubCommand(manager),
-		upOneSubCommand(manager),
-		upToSubCommand(manager),
-		verifySubCommand(manager),
-		versionSubCommand(manager),
+		describeSubCommand(manager, out),
+		headsSubCommand(manager, out),
+		upSubCommand(manager, out),
+		upOneSubCommand(manager, out),
+		upToSubCommand(manager, out),
+		verifySubCommand(manager, out),
+		versionSubCommand(manager, out),
+		statusSubCommand(manager, out),
+		upgradeMetadataTableSubCommand(manager, out),
+		stampSubCommand(manager, out),
+		repairSubCommand(manager, out),
 	)
 }
 
-func describeSubCommand(manager *golembic.Manager) *cobra.Command {
+func describeSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
 	short := "Describe the registered sequence of migrations"
 	long := strings.Join([]string{
 		short + ".",
@@ -38,6 +46,11 @@ func describeSubCommand(manager *golembic.Manager) *cobra.Command {
 				err = poolFinalize(manager, err)
 			}()
 
+			if out.isJSON() {
+				err = out.emit(cmd, newDescribeJSON(manager.Sequence.All()), nil)
+				return
+			}
+
 			ctx := context.Background()
 			err = manager.Describe(ctx)
 			return
@@ -46,8 +59,39 @@ func describeSubCommand(manager *golembic.Manager) *cobra.Command {
 	return cmd
 }
 
-func upSubCommand(manager *golembic.Manager) *cobra.Command {
+func headsSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
+	short := "Display the heads of the registered sequence of migrations"
+	long := strings.Join([]string{
+		short + ".",
+		"",
+		"If the sequence has diverged into multiple heads (e.g. two branches",
+		"added a migration with the same previous revision), this will fail and",
+		"a merge migration is needed. This does not make any connection to the",
+		"database.",
+	}, "\n")
+	cmd := &cobra.Command{
+		Use:   "heads",
+		Short: short,
+		Long:  long,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer func() {
+				err = poolFinalize(manager, err)
+			}()
+
+			ctx := context.Background()
+			err = manager.Heads(ctx)
+			err = out.emit(cmd, newHeadsJSON(manager.Sequence.Heads()), err)
+			return
+		},
+	}
+	return cmd
+}
+
+func upSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
 	verifyHistory := false
+	dryRun := false
+	retry := golembic.RetryPolicy{}
+	singleTransaction := false
 	cmd := &cobra.Command{
 		Use:   "up",
 		Short: "Run all migrations that have not yet been applied",
@@ -57,12 +101,22 @@ func upSubCommand(manager *golembic.Manager) *cobra.Command {
 			}()
 
 			ctx := context.Background()
-			err = manager.Up(ctx, golembic.OptApplyVerifyHistory(verifyHistory))
+			report, err := manager.UpWithReport(
+				ctx,
+				golembic.OptApplyVerifyHistory(verifyHistory),
+				golembic.OptApplyDryRun(dryRun),
+				golembic.OptApplyRetry(retry),
+				golembic.OptApplySingleTransaction(singleTransaction),
+			)
+			err = out.emit(cmd, newApplyReportJSON(report), err)
 			return
 		},
 	}
 
 	addVerifyHistory(cmd, &verifyHistory)
+	addDryRun(cmd, &dryRun)
+	addRetry(cmd, &retry)
+	addSingleTransaction(cmd, &singleTransaction)
 	return cmd
 }
 
@@ -75,8 +129,55 @@ func addVerifyHistory(cmd *cobra.Command, verifyHistory *bool) {
 	)
 }
 
-func upOneSubCommand(manager *golembic.Manager) *cobra.Command {
+func addDryRun(cmd *cobra.Command, dryRun *bool) {
+	cmd.PersistentFlags().BoolVar(
+		dryRun,
+		"dry-run",
+		false,
+		"If set, display the migrations that would be applied without applying them",
+	)
+}
+
+func addRetry(cmd *cobra.Command, retry *golembic.RetryPolicy) {
+	cmd.PersistentFlags().IntVar(
+		&retry.MaxAttempts,
+		"retry-attempts",
+		1,
+		"The maximum number of attempts for a migration that fails with a retryable error (e.g. a lock timeout)",
+	)
+	cmd.PersistentFlags().DurationVar(
+		&retry.Backoff,
+		"retry-backoff",
+		time.Second,
+		"The time to wait before the first retry; this doubles for each subsequent retry",
+	)
+	cmd.PersistentFlags().DurationVar(
+		&retry.MaxBackoff,
+		"retry-max-backoff",
+		time.Minute,
+		"The maximum time to wait between retries (before jitter); if 0, the wait keeps doubling",
+	)
+	cmd.PersistentFlags().DurationVar(
+		&retry.Jitter,
+		"retry-jitter",
+		500*time.Millisecond,
+		"The maximum random time added to each wait between retries",
+	)
+}
+
+func addSingleTransaction(cmd *cobra.Command, singleTransaction *bool) {
+	cmd.PersistentFlags().BoolVar(
+		singleTransaction,
+		"single-transaction",
+		false,
+		"If set, apply all of the migrations in a single transaction (requires transactional DDL)",
+	)
+}
+
+func upOneSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
 	verifyHistory := false
+	dryRun := false
+	retry := golembic.RetryPolicy{}
 	cmd := &cobra.Command{
 		Use:   "up-one",
 		Short: "Run the first migration that has not yet been applied",
@@ -86,17 +187,28 @@ func upOneSubCommand(manager *golembic.Manager) *cobra.Command {
 			}()
 
 			ctx := context.Background()
-			err = manager.UpOne(ctx, golembic.OptApplyVerifyHistory(verifyHistory))
+			report, err := manager.UpOneWithReport(
+				ctx,
+				golembic.OptApplyVerifyHistory(verifyHistory),
+				golembic.OptApplyDryRun(dryRun),
+				golembic.OptApplyRetry(retry),
+			)
+			err = out.emit(cmd, newApplyReportJSON(report), err)
 			return
 		},
 	}
 
 	addVerifyHistory(cmd, &verifyHistory)
+	addDryRun(cmd, &dryRun)
+	addRetry(cmd, &retry)
 	return cmd
 }
 
-func upToSubCommand(manager *golembic.Manager) *cobra.Command {
+func upToSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
 	verifyHistory := false
+	dryRun := false
+	retry := golembic.RetryPolicy{}
+	singleTransaction := false
 	revision := ""
 	cmd := &cobra.Command{
 		Use:   "up-to",
@@ -107,11 +219,15 @@ func upToSubCommand(manager *golembic.Manager) *cobra.Command {
 			}()
 
 			ctx := context.Background()
-			err = manager.UpTo(
+			report, err := manager.UpToWithReport(
 				ctx,
 				golembic.OptApplyRevision(revision),
 				golembic.OptApplyVerifyHistory(verifyHistory),
+				golembic.OptApplyDryRun(dryRun),
+				golembic.OptApplyRetry(retry),
+				golembic.OptApplySingleTransaction(singleTransaction),
 			)
+			err = out.emit(cmd, newApplyReportJSON(report), err)
 			return
 		},
 	}
@@ -125,10 +241,13 @@ func upToSubCommand(manager *golembic.Manager) *cobra.Command {
 	cobra.MarkFlagRequired(cmd.PersistentFlags(), "revision")
 
 	addVerifyHistory(cmd, &verifyHistory)
+	addDryRun(cmd, &dryRun)
+	addRetry(cmd, &retry)
+	addSingleTransaction(cmd, &singleTransaction)
 	return cmd
 }
 
-func verifySubCommand(manager *golembic.Manager) *cobra.Command {
+func verifySubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "verify",
 		Short: "Verify the stored migration metadata against the registered sequence",
@@ -138,6 +257,15 @@ func verifySubCommand(manager *golembic.Manager) *cobra.Command {
 			}()
 
 			ctx := context.Background()
+			if out.isJSON() {
+				entries, statusErr := manager.Status(ctx)
+				if statusErr == nil {
+					statusErr = golembic.DirtyStatusError(entries)
+				}
+				err = out.emit(cmd, newStatusJSON(entries), statusErr)
+				return
+			}
+
 			err = manager.Verify(ctx)
 			return
 		},
@@ -145,7 +273,7 @@ func verifySubCommand(manager *golembic.Manager) *cobra.Command {
 	return cmd
 }
 
-func versionSubCommand(manager *golembic.Manager) *cobra.Command {
+func versionSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
 	verifyHistory := false
 	cmd := &cobra.Command{
 		Use:   "version",
@@ -156,6 +284,17 @@ func versionSubCommand(manager *golembic.Manager) *cobra.Command {
 			}()
 
 			ctx := context.Background()
+			if out.isJSON() {
+				migration, versionErr := manager.GetVersion(ctx, golembic.OptApplyVerifyHistory(verifyHistory))
+				var result *versionJSON
+				if versionErr == nil {
+					vj := newVersionJSON(migration)
+					result = &vj
+				}
+				err = out.emit(cmd, result, versionErr)
+				return
+			}
+
 			err = manager.Version(ctx, golembic.OptApplyVerifyHistory(verifyHistory))
 			return
 		},
@@ -165,6 +304,204 @@ func versionSubCommand(manager *golembic.Manager) *cobra.Command {
 	return cmd
 }
 
+func statusSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Display the registered migrations and whether each has been applied or is pending",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer func() {
+				err = poolFinalize(manager, err)
+			}()
+
+			ctx := context.Background()
+			entries, err := manager.Status(ctx)
+			if out.isJSON() {
+				err = out.emit(cmd, newStatusJSON(entries), err)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			err = writeStatusTable(cmd.OutOrStdout(), entries)
+			return
+		},
+	}
+	return cmd
+}
+
+func stampSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
+	revision := ""
+	short := "Record migrations up to a fixed revision as applied without running them"
+	long := strings.Join([]string{
+		short + ".",
+		"",
+		"This is intended for adopting golembic on an existing database or for",
+		"recording a migration that was applied by hand.",
+	}, "\n")
+	cmd := &cobra.Command{
+		Use:   "stamp",
+		Short: short,
+		Long:  long,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer func() {
+				err = poolFinalize(manager, err)
+			}()
+
+			ctx := context.Background()
+			err = manager.Stamp(ctx, revision)
+			var result *stampJSON
+			if err == nil {
+				result = &stampJSON{Revision: revision}
+			}
+			err = out.emit(cmd, result, err)
+			return
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(
+		&revision,
+		"revision",
+		"",
+		"The revision to record migrations as applied up to",
+	)
+	cobra.MarkFlagRequired(cmd.PersistentFlags(), "revision")
+
+	return cmd
+}
+
+func repairSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
+	revision := ""
+	action := ""
+	releaseLock := false
+	short := "Resolve a dirty migration that was started but did not complete"
+	long := strings.Join([]string{
+		short + ".",
+		"",
+		"A non-transactional migration is recorded as dirty before it runs and",
+		"marked as complete once it succeeds. If it fails (or the process dies)",
+		"the database may be partially migrated and no more migrations can be",
+		"applied. After inspecting the database, use --action=complete if the",
+		"migration was fully applied or --action=revert if it was not (so it",
+		"will be run again).",
+		"",
+		"If a process dies while holding the migration lock and the lock is not",
+		"released along with the process (e.g. for SQLite), use --release-lock",
+		"once that process is confirmed to no longer be running.",
+	}, "\n")
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: short,
+		Long:  long,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer func() {
+				err = poolFinalize(manager, err)
+			}()
+
+			ctx := context.Background()
+			var result *repairJSON
+			if releaseLock {
+				err = manager.ReleaseStaleLock(ctx)
+				if err == nil {
+					result = &repairJSON{ReleasedLock: true}
+				}
+			} else {
+				err = manager.Repair(ctx, revision, golembic.RepairAction(action))
+				if err == nil {
+					result = &repairJSON{Revision: revision, Action: action}
+				}
+			}
+			err = out.emit(cmd, result, err)
+			return
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(
+		&revision,
+		"revision",
+		"",
+		"The revision of the dirty migration",
+	)
+	cmd.PersistentFlags().StringVar(
+		&action,
+		"action",
+		"",
+		fmt.Sprintf("How to resolve the dirty migration, one of %q or %q", golembic.RepairComplete, golembic.RepairRevert),
+	)
+	cmd.PersistentFlags().BoolVar(
+		&releaseLock,
+		"release-lock",
+		false,
+		"Release a stale migration lock held by a process that is no longer running",
+	)
+	cmd.MarkFlagsRequiredTogether("revision", "action")
+	cmd.MarkFlagsOneRequired("revision", "release-lock")
+	cmd.MarkFlagsMutuallyExclusive("revision", "release-lock")
+	cmd.MarkFlagsMutuallyExclusive("action", "release-lock")
+
+	return cmd
+}
+
+func upgradeMetadataTableSubCommand(manager *golembic.Manager, out *outputOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade-metadata-table",
+		Short: "Upgrade the migration metadata table if it was created by an older version of golembic",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer func() {
+				err = poolFinalize(manager, err)
+			}()
+
+			ctx := context.Background()
+			err = manager.UpgradeMetadataTable(ctx)
+			if err != nil {
+				err = out.emit(cmd, nil, err)
+				return
+			}
+
+			version, err := manager.MetadataTableVersion(ctx)
+			if out.isJSON() {
+				var result *metadataTableJSON
+				if err == nil {
+					result = &metadataTableJSON{Table: manager.MetadataTable, Version: version}
+				}
+				err = out.emit(cmd, result, err)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			manager.Log.Printf("Metadata table %s is at version %d", manager.MetadataTable, version)
+			return
+		},
+	}
+	return cmd
+}
+
+func writeStatusTable(w io.Writer, entries []golembic.StatusEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tREVISION\tSTATUS\tAPPLIED AT\tTRANSACTIONAL\tDESCRIPTION")
+	for i, entry := range entries {
+		status := "pending"
+		appliedAt := "-"
+		if entry.Applied {
+			status = "applied"
+			appliedAt = entry.AppliedAt.Format(time.RFC3339Nano)
+		}
+		if entry.Dirty {
+			status = "dirty"
+		}
+
+		fmt.Fprintf(
+			tw,
+			"%d\t%s\t%s\t%s\t%t\t%s\n",
+			i, entry.Revision, status, appliedAt, entry.Transactional, entry.ExtendedDescription(),
+		)
+	}
+
+	return tw.Flush()
+}
+
 // poolFinalize is intended to be used in `defer` blocks to ensure that a SQL
 // database connection pool on a manager is always closed after the manager is
 // used.
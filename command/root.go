@@ -2,6 +2,8 @@ package command
 
 import (
 	"errors"
+	"io/fs"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -10,8 +12,18 @@ import (
 
 // MakeRootCommand creates a `cobra` command that is bound to a sequence of
 // migrations. The flags for the root command and relevant subcommands will
-// be used to configure a `Manager`.
+// be used to configure a `Manager`. The `.sql` files for the migrations will
+// be read from the `--sql-directory` flag (or the current directory if the
+// flag is not set).
 func MakeRootCommand(rm RegisterMigrations) (*cobra.Command, error) {
+	return MakeRootCommandFS(rm, nil)
+}
+
+// MakeRootCommandFS creates a `cobra` command that is bound to a sequence of
+// migrations. It differs from `MakeRootCommand()` in that the `.sql` files
+// for the migrations will be read from `fsys` (e.g. an `embed.FS`) unless
+// the `--sql-directory` flag is set.
+func MakeRootCommandFS(rm RegisterMigrations, fsys fs.FS) (*cobra.Command, error) {
 	if rm == nil {
 		return nil, errors.New("Root command requires a non-nil migrations sequence")
 	}
@@ -23,6 +35,8 @@ func MakeRootCommand(rm RegisterMigrations) (*cobra.Command, error) {
 	}
 
 	sqlDirectory := ""
+	appliedBy := ""
+	out := &outputOptions{}
 	cmd := &cobra.Command{
 		Use:           "golembic",
 		Short:         "Manage database migrations for Go codebases",
@@ -35,7 +49,19 @@ func MakeRootCommand(rm RegisterMigrations) (*cobra.Command, error) {
 			//       if necessary and invoke this function. See:
 			//       - https://github.com/spf13/cobra/issues/216
 			//       - https://github.com/spf13/cobra/issues/252
-			migrations, err := rm(sqlDirectory, engine)
+			err := out.validate()
+			if err != nil {
+				return err
+			}
+			// In JSON mode, STDOUT is reserved for the JSON document.
+			if out.isJSON() {
+				manager.Log = &stderrPrintf{}
+			}
+			if appliedBy != "" {
+				manager.AppliedBy = appliedBy
+			}
+
+			migrations, err := rm(sqlFS(sqlDirectory, fsys), engine)
 			if err != nil {
 				return err
 			}
@@ -59,6 +85,41 @@ func MakeRootCommand(rm RegisterMigrations) (*cobra.Command, error) {
 		"Path to a directory containing \".sql\" migration files",
 	)
 
+	cmd.PersistentFlags().DurationVar(
+		&manager.MigrationLockTimeout,
+		"migration-lock-timeout",
+		golembic.DefaultMigrationLockTimeout,
+		"The maximum time to wait to acquire the lock held while migrations are applied (0 means wait indefinitely)",
+	)
+
+	cmd.PersistentFlags().BoolVar(
+		&manager.AutoUpgradeMetadataTable,
+		"auto-upgrade-metadata-table",
+		false,
+		"Flag indicating that the migration metadata table should be upgraded if it was created by an older version of golembic",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&appliedBy,
+		"applied-by",
+		"",
+		"The user recorded in the migration metadata table when a migration is applied (defaults to the current user)",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&manager.ApplicationVersion,
+		"application-version",
+		"",
+		"The application version (e.g. a release tag) recorded in the migration metadata table when a migration is applied",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&out.Format,
+		"output",
+		outputText,
+		"The output format, one of \"text\" or \"json\"",
+	)
+
 	cmd.PersistentFlags().BoolVar(
 		&manager.DevelopmentMode,
 		"dev",
@@ -66,20 +127,47 @@ func MakeRootCommand(rm RegisterMigrations) (*cobra.Command, error) {
 		"Flag indicating that the migrations should be run in development mode",
 	)
 
+	// Add provider agnostic sub-commands.
+	cmd.AddCommand(newSubCommand(manager, &sqlDirectory, out))
 	// Add PostgreSQL specific sub-commands.
 	postgres, err := postgresSubCommand(manager, cmd, &engine)
 	if err != nil {
 		return nil, err
 	}
+	out.emitOnPreRunError(postgres)
 	cmd.AddCommand(postgres)
-	registerProviderSubcommands(postgres, manager)
+	registerProviderSubcommands(postgres, manager, out)
 	// Add MySQL specific sub-commands.
 	mysql, err := mysqlSubCommand(manager, cmd, &engine)
 	if err != nil {
 		return nil, err
 	}
+	out.emitOnPreRunError(mysql)
 	cmd.AddCommand(mysql)
-	registerProviderSubcommands(mysql, manager)
+	registerProviderSubcommands(mysql, manager, out)
+	// Add SQLite specific sub-commands.
+	sqlite3, err := sqlite3SubCommand(manager, cmd, &engine)
+	if err != nil {
+		return nil, err
+	}
+	out.emitOnPreRunError(sqlite3)
+	cmd.AddCommand(sqlite3)
+	registerProviderSubcommands(sqlite3, manager, out)
 
 	return cmd, nil
 }
+
+// sqlFS determines the filesystem where `.sql` files will be read from. An
+// explicit `sqlDirectory` takes precedence over `fsys` and if neither is set,
+// the current working directory is used.
+func sqlFS(sqlDirectory string, fsys fs.FS) fs.FS {
+	if sqlDirectory != "" {
+		return os.DirFS(sqlDirectory)
+	}
+
+	if fsys != nil {
+		return fsys
+	}
+
+	return os.DirFS(".")
+}
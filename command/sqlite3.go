@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dhermes/golembic"
+	"github.com/dhermes/golembic/sqlite3"
+)
+
+func sqlite3SubCommand(manager *golembic.Manager, parent *cobra.Command, engine *string) (*cobra.Command, error) {
+	provider, err := sqlite3.New()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := provider.Config
+	file := ""
+	cmd := &cobra.Command{
+		Use:   "sqlite3",
+		Short: "Manage database migrations for a SQLite database",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			*engine = "sqlite3"
+
+			// NOTE: Manually invoke `PersistentPreRunE` on the parent to enable
+			//       chaining (the behavior in `cobra` is to replace as the
+			//       tree is traversed). See:
+			//       - https://github.com/spf13/cobra/issues/216
+			//       - https://github.com/spf13/cobra/issues/252
+			if parent != nil && parent.PersistentPreRunE != nil {
+				err := parent.PersistentPreRunE(cmd, args)
+				if err != nil {
+					return err
+				}
+			}
+
+			if file != "" {
+				if cmd.Flags().Changed("data-source-name") {
+					return fmt.Errorf("Cannot set both --file and --data-source-name")
+				}
+
+				fileFull, err := filepath.Abs(file)
+				if err != nil {
+					return err
+				}
+
+				cfg.DataSourceName = fmt.Sprintf("file:%s?cache=shared", fileFull)
+			}
+
+			manager.Provider = provider
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(
+		&cfg.DataSourceName,
+		"data-source-name",
+		cfg.DataSourceName,
+		"The DSN or connection string to use when connecting to SQLite",
+	)
+	cmd.PersistentFlags().StringVar(
+		&file,
+		"file",
+		"",
+		"Path to a SQLite database file (an alternative to --data-source-name)",
+	)
+	cmd.PersistentFlags().StringVar(
+		&cfg.DriverName,
+		"driver-name",
+		cfg.DriverName,
+		"The name of SQL driver to be used when creating a new database connection pool",
+	)
+
+	return cmd, nil
+}
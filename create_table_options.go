@@ -32,6 +32,62 @@ func OptCreateTableCreatedAt(createdAt string) CreateTableOption {
 	}
 }
 
+// OptCreateTableMerges sets the `Merges` field in create table options.
+func OptCreateTableMerges(merges string) CreateTableOption {
+	return func(ctp *CreateTableParameters) {
+		ctp.Merges = merges
+		return
+	}
+}
+
+// OptCreateTableDirty sets the `Dirty` field in create table options.
+func OptCreateTableDirty(dirty string) CreateTableOption {
+	return func(ctp *CreateTableParameters) {
+		ctp.Dirty = dirty
+		return
+	}
+}
+
+// OptCreateTableChecksum sets the `Checksum` field in create table options.
+func OptCreateTableChecksum(checksum string) CreateTableOption {
+	return func(ctp *CreateTableParameters) {
+		ctp.Checksum = checksum
+		return
+	}
+}
+
+// OptCreateTableAppliedBy sets the `AppliedBy` field in create table options.
+func OptCreateTableAppliedBy(appliedBy string) CreateTableOption {
+	return func(ctp *CreateTableParameters) {
+		ctp.AppliedBy = appliedBy
+		return
+	}
+}
+
+// OptCreateTableHostname sets the `Hostname` field in create table options.
+func OptCreateTableHostname(hostname string) CreateTableOption {
+	return func(ctp *CreateTableParameters) {
+		ctp.Hostname = hostname
+		return
+	}
+}
+
+// OptCreateTableDurationMS sets the `DurationMS` field in create table options.
+func OptCreateTableDurationMS(durationMS string) CreateTableOption {
+	return func(ctp *CreateTableParameters) {
+		ctp.DurationMS = durationMS
+		return
+	}
+}
+
+// OptCreateTableAppVersion sets the `AppVersion` field in create table options.
+func OptCreateTableAppVersion(appVersion string) CreateTableOption {
+	return func(ctp *CreateTableParameters) {
+		ctp.AppVersion = appVersion
+		return
+	}
+}
+
 // OptCreateTableConstraints sets the `Constraints` field in create table options.
 func OptCreateTableConstraints(constraints string) CreateTableOption {
 	return func(ctp *CreateTableParameters) {
@@ -14,9 +14,10 @@
 // - Avoid all import time side effects caused either by importing a package that
 // uses `init()` or by requiring migrations files to use `init()`
 //
-// - Down migrations are not supported. The idea being that the risk of data loss
-// from a down migration is not worth it and that writing down migrations can
-// be more challenging than writing up migrations.
+// - Down migrations are optional. The risk of data loss from a down migration
+// is often not worth it and writing down migrations can be more challenging
+// than writing up migrations, but when a migration provides one it can be
+// rolled back (e.g. after a bad deploy) via `DownOne()` or `DownTo()`.
 //
 // The design allows for running "arbitrary" code inside migrations
 // so that even non-SQL tasks can be tracked as a "run-once" migration.
@@ -33,9 +33,98 @@ var (
 	// ErrMigrationMismatch is the error returned when the migration stored in
 	// SQL does not match the registered migration.
 	ErrMigrationMismatch = errors.New("Migration stored in SQL doesn't match sequence")
+	// ErrChecksumMismatch is the error returned when the checksum stored in
+	// SQL for a migration does not match the checksum of the registered
+	// migration, i.e. the migration was modified after it was applied.
+	ErrChecksumMismatch = errors.New("Migration checksum stored in SQL doesn't match sequence")
 	// ErrCannotInvokeUp is the error returned when a migration cannot invoke the
 	// up function (e.g. if it is `nil`).
 	ErrCannotInvokeUp = errors.New("Cannot invoke up function for a migration")
+	// ErrCannotInvokeDown is the error returned when a migration cannot invoke
+	// the down function (e.g. if it is `nil`).
+	ErrCannotInvokeDown = errors.New("Cannot invoke down function for a migration")
+	// ErrNotApplied is the error returned when a migration is expected to
+	// have been applied, but is not present in the migrations metadata table.
+	ErrNotApplied = errors.New("Migration has not been applied")
+	// ErrLockNotAcquired is the error returned when the migration lock could
+	// not be acquired (e.g. if the lock timeout was exceeded).
+	ErrLockNotAcquired = errors.New("Could not acquire migration lock")
+	// ErrLockNotReleased is the error returned when the migration lock could
+	// not be released (e.g. if it was not held).
+	ErrLockNotReleased = errors.New("Could not release migration lock")
+	// ErrStaleLockUnsupported is the error returned when attempting to
+	// release a stale migration lock for a provider where a lock can't
+	// outlive the process that acquired it.
+	ErrStaleLockUnsupported = errors.New("Provider does not support releasing a stale migration lock")
+	// ErrNoMigrations is the error returned when attempting to load a
+	// sequence of migrations from a directory with no `.sql` files.
+	ErrNoMigrations = errors.New("No migrations found")
+	// ErrMalformedFilename is the error returned when a `.sql` file does not
+	// follow the naming convention for migrations.
+	ErrMalformedFilename = errors.New("Migration filename is malformed")
+	// ErrMalformedHeader is the error returned when the header comment block
+	// in a `.sql` file contains an invalid value.
+	ErrMalformedHeader = errors.New("Migration file header is malformed")
+	// ErrDuplicateNumber is the error returned when two `.sql` files have
+	// the same number.
+	ErrDuplicateNumber = errors.New("Migration files have duplicate numbers")
+	// ErrSequenceGap is the error returned when the numbers of `.sql` files
+	// are not consecutive.
+	ErrSequenceGap = errors.New("Migration files have a gap in numbering")
+	// ErrDuplicateParent is the error returned when attempting to register a
+	// merge migration that lists the same parent more than once.
+	ErrDuplicateParent = errors.New("Cannot register a migration with duplicate parents")
+	// ErrMultipleHeads is the error returned when a sequence of migrations has
+	// diverged into more than one head and a merge migration is needed.
+	ErrMultipleHeads = errors.New("Sequence has multiple heads; a merge migration is required")
+	// ErrNoRoot is the error returned when a sequence of migrations does not
+	// have a root migration.
+	ErrNoRoot = errors.New("Sequence does not have a root migration")
+	// ErrMultipleRoots is the error returned when a sequence of migrations
+	// has more than one (or no) migration without a previous migration.
+	ErrMultipleRoots = errors.New("Sequence must have exactly one root migration")
+	// ErrOrphanedMigration is the error returned when a migration in a
+	// sequence has a parent that is not registered.
+	ErrOrphanedMigration = errors.New("Migration has a parent that is not registered")
+	// ErrSequenceCycle is the error returned when the parents of migrations in
+	// a sequence form a cycle.
+	ErrSequenceCycle = errors.New("Sequence contains a cycle")
+	// ErrSequenceOutOfOrder is the error returned when a migration in a
+	// sequence is ordered before one of its parents.
+	ErrSequenceOutOfOrder = errors.New("Migration is ordered before its parent")
+	// ErrUnreachableMigration is the error returned when a migration in a
+	// sequence cannot be reached from the root migration.
+	ErrUnreachableMigration = errors.New("Migration is not reachable from the root migration")
+	// ErrSingleTransactionUnsupported is the error returned when a batch of
+	// migrations cannot be applied in a single transaction, either because the
+	// provider does not support transactional DDL or because a migration is
+	// not transactional.
+	ErrSingleTransactionUnsupported = errors.New("Cannot apply migrations in a single transaction")
+	// ErrTimeoutUnsupported is the error returned when a migration overrides
+	// the lock or statement timeout but the provider does not support it.
+	ErrTimeoutUnsupported = errors.New("Provider does not support migration timeouts")
+	// ErrInvalidRetryPolicy is the error returned when a retry policy has a
+	// negative number of attempts, backoff or jitter.
+	ErrInvalidRetryPolicy = errors.New("Invalid retry policy")
+	// ErrMigrationDirty is the error returned when a non-transactional
+	// migration was started but did not complete (or could not be marked as
+	// complete in the migrations metadata table), i.e. the database may be in
+	// a "dirty" state. It is also returned when attempting to apply (or roll
+	// back) migrations while a dirty row exists; see `Manager.Repair()`.
+	ErrMigrationDirty = errors.New("Migration is dirty; it may have been partially applied")
+	// ErrNotDirty is the error returned when attempting to repair a migration
+	// that is not dirty.
+	ErrNotDirty = errors.New("Migration is not dirty")
+	// ErrInvalidRepairAction is the error returned when attempting to repair
+	// a dirty migration with an unknown action.
+	ErrInvalidRepairAction = errors.New("Invalid repair action")
+	// ErrVetoed is the error returned when an observer (see `Observer`)
+	// vetoes a run or a migration.
+	ErrVetoed = errors.New("Vetoed by an observer")
+	// ErrMetadataTableOutdated is the error returned when the migrations
+	// metadata table was created by an older version of this package and has
+	// not been upgraded, e.g. via `UpgradeMetadataTable()`.
+	ErrMetadataTableOutdated = errors.New("Migrations metadata table is outdated")
 	// ErrCannotPassMilestone is the error returned when a migration sequence
 	// contains a milestone migration that is **NOT** the last step.
 	ErrCannotPassMilestone = errors.New("If a migration sequence contains a milestone, it must be the last migration")
@@ -6,6 +6,7 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 
 	"github.com/dhermes/golembic/command"
 	"github.com/dhermes/golembic/examples"
@@ -18,7 +19,12 @@ var (
 )
 
 func run() error {
-	cmd, err := command.MakeRootCommand(examples.AllMigrations)
+	fsys, err := examples.SQLFiles()
+	if err != nil {
+		return err
+	}
+
+	cmd, err := command.MakeRootCommandFS(examples.AllMigrations, fsys)
 	if err != nil {
 		return err
 	}
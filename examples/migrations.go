@@ -1,14 +1,26 @@
 package examples
 
 import (
-	"path/filepath"
+	"embed"
+	"io/fs"
 
 	"github.com/dhermes/golembic"
 )
 
-// AllMigrations returns a sequence of migrations based on a directory
+var (
+	//go:embed sql/*.sql
+	embedded embed.FS
+)
+
+// SQLFiles returns the `.sql` files for the example migrations, embedded
+// in the current package.
+func SQLFiles() (fs.FS, error) {
+	return fs.Sub(embedded, "sql")
+}
+
+// AllMigrations returns a sequence of migrations based on a filesystem
 // containing `.sql` files.
-func AllMigrations(sqlDirectory, engine string) (*golembic.Migrations, error) {
+func AllMigrations(fsys fs.FS, engine string) (*golembic.Migrations, error) {
 	addUsersEmailFile := "0005_add_users_email_index_concurrently.sql"
 	if engine == "mysql" {
 		addUsersEmailFile = "0005_add_users_email_index_lock_none.sql"
@@ -19,7 +31,7 @@ func AllMigrations(sqlDirectory, engine string) (*golembic.Migrations, error) {
 	root, err := golembic.NewMigration(
 		golembic.OptRevision("c9b52448285b"),
 		golembic.OptDescription("Create users table"),
-		golembic.OptUpFromFile(filepath.Join(sqlDirectory, "0001_create_users_table.sql")),
+		golembic.OptUpFromFS(fsys, "0001_create_users_table.sql"),
 	)
 	if err != nil {
 		return nil, err
@@ -34,38 +46,38 @@ func AllMigrations(sqlDirectory, engine string) (*golembic.Migrations, error) {
 			golembic.OptPrevious("c9b52448285b"),
 			golembic.OptRevision("f1be62155239"),
 			golembic.OptDescription("Seed data in users table"),
-			golembic.OptUpFromFile(filepath.Join(sqlDirectory, "0002_seed_users_table.sql")),
+			golembic.OptUpFromFS(fsys, "0002_seed_users_table.sql"),
 		},
 		[]golembic.MigrationOption{
 			golembic.OptPrevious("f1be62155239"),
 			golembic.OptRevision("dce8812d7b6f"),
 			golembic.OptDescription("Add city column to users table"),
-			golembic.OptUpFromFile(filepath.Join(sqlDirectory, "0003_add_users_city_column.sql")),
+			golembic.OptUpFromFS(fsys, "0003_add_users_city_column.sql"),
 		},
 		[]golembic.MigrationOption{
 			golembic.OptPrevious("dce8812d7b6f"),
 			golembic.OptRevision("0430566018cc"),
 			golembic.OptDescription("Rename the root user"),
 			golembic.OptMilestone(true),
-			golembic.OptUpFromFile(filepath.Join(sqlDirectory, "0004_rename_root.sql")),
+			golembic.OptUpFromFS(fsys, "0004_rename_root.sql"),
 		},
 		[]golembic.MigrationOption{
 			golembic.OptPrevious("0430566018cc"),
 			golembic.OptRevision("0501ccd1d98c"),
 			golembic.OptDescription("Add index on user emails (concurrently)"),
-			golembic.OptUpConnFromFile(filepath.Join(sqlDirectory, addUsersEmailFile)),
+			golembic.OptUpConnFromFS(fsys, addUsersEmailFile),
 		},
 		[]golembic.MigrationOption{
 			golembic.OptPrevious("0501ccd1d98c"),
 			golembic.OptRevision("e2d4eecb1841"),
 			golembic.OptDescription("Create books table"),
-			golembic.OptUpFromFile(filepath.Join(sqlDirectory, "0006_create_books_table.sql")),
+			golembic.OptUpFromFS(fsys, "0006_create_books_table.sql"),
 		},
 		[]golembic.MigrationOption{
 			golembic.OptPrevious("e2d4eecb1841"),
 			golembic.OptRevision("432f690fcbda"),
 			golembic.OptDescription("Create movies table"),
-			golembic.OptUpFromFile(filepath.Join(sqlDirectory, "0007_create_movies_table.sql")),
+			golembic.OptUpFromFS(fsys, "0007_create_movies_table.sql"),
 		},
 	)
 	if err != nil {
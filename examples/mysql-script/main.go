@@ -93,7 +93,7 @@ func run() (err error) {
 		return
 	}
 
-	migrations, err := examples.AllMigrations(c.GolembicSQLDir, "mysql")
+	migrations, err := examples.AllMigrations(os.DirFS(c.GolembicSQLDir), "mysql")
 	if err != nil {
 		return
 	}
@@ -98,7 +98,7 @@ func run() (err error) {
 		return
 	}
 
-	migrations, err := examples.AllMigrations(c.GolembicSQLDir, "postgres")
+	migrations, err := examples.AllMigrations(os.DirFS(c.GolembicSQLDir), "postgres")
 	if err != nil {
 		return
 	}
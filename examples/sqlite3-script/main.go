@@ -69,7 +69,7 @@ func run() (err error) {
 		return
 	}
 
-	migrations, err := examples.AllMigrations(c.GolembicSQLDir, "sqlite3")
+	migrations, err := examples.AllMigrations(os.DirFS(c.GolembicSQLDir), "sqlite3")
 	if err != nil {
 		return
 	}
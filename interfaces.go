@@ -24,12 +24,25 @@ type UpMigration = func(context.Context, *sql.Tx) error
 // should only be used in rare situations.
 type UpMigrationConn = func(context.Context, *sql.Conn) error
 
+// DownMigration defines a function interface to be used for down / reverse
+// migrations. This mirrors `UpMigration`: the SQL transaction will be started
+// **before** `DownMigration` is invoked and will be committed **after** the
+// `DownMigration` exits without error. In addition to the contents of
+// `DownMigration`, the row for the migration will be deleted from the
+// migrations metadata table as part of the transaction.
+type DownMigration = func(context.Context, *sql.Tx) error
+
+// DownMigrationConn defines a function interface to be used for down / reverse
+// migrations. This is the non-transactional form of `DownMigration` and
+// should only be used in rare situations.
+type DownMigrationConn = func(context.Context, *sql.Conn) error
+
 // migrationsFilter defines a function interface that filters migrations
-// based on the `latest` revision. It's expected that a migrations filter
-// will enclose other state such as a `Manager`. In addition to returning
-// a slice of filtered migrations, it will also return a count of the
-// number of existing migrations that were filtered out.
-type migrationsFilter = func(latest string) (int, []Migration, error)
+// based on the revisions that have already been `applied`. It's expected that
+// a migrations filter will enclose other state such as a `Manager`. In
+// addition to returning a slice of filtered migrations, it will also return a
+// count of the number of existing migrations that were filtered out.
+type migrationsFilter = func(applied []string) (int, []Migration, error)
 
 // EngineProvider describes the interface required for a database engine.
 type EngineProvider interface {
@@ -56,6 +69,155 @@ type EngineProvider interface {
 	TableExistsSQL() string
 }
 
+// LockProvider describes an optional interface that an `EngineProvider` can
+// satisfy to support a cross-process lock. The lock will be held (on a
+// dedicated connection) for the duration of an "up" or "down" command so that
+// concurrent processes (e.g. two pods starting at once) cannot race when
+// applying migrations.
+type LockProvider interface {
+	// AcquireLock acquires a named lock using `conn`, waiting at most
+	// `timeout` for the lock to become available. A `timeout` of zero means
+	// waiting indefinitely.
+	AcquireLock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error
+	// ReleaseLock releases a named lock that was acquired with `conn`.
+	ReleaseLock(ctx context.Context, conn *sql.Conn, name string) error
+}
+
+// StaleLockProvider describes an optional interface that a `LockProvider`
+// can satisfy if a lock can outlive the process that acquired it, e.g. a lock
+// stored as a row in a table rather than tied to a database session.
+type StaleLockProvider interface {
+	// ForceReleaseLock releases a named lock regardless of which process
+	// acquired it.
+	ForceReleaseLock(ctx context.Context, conn *sql.Conn, name string) error
+}
+
+// SchemaProvider describes an optional interface that an `EngineProvider`
+// can satisfy to describe the existing schema. This is used when upgrading the
+// migrations metadata table so that each step of an upgrade that was
+// interrupted part way through (e.g. on an engine where each DDL statement
+// causes an implicit commit) is skipped if it was already applied.
+type SchemaProvider interface {
+	// ColumnExistsSQL returns a SQL query that returns a row if a column
+	// exists; the parameters are the (unquoted) table and column names.
+	ColumnExistsSQL() string
+	// IndexExistsSQL returns a SQL query that returns a row if an index (or
+	// a `UNIQUE` constraint) exists; the parameters are the (unquoted) table
+	// and index names.
+	IndexExistsSQL() string
+}
+
+// DropConstraintProvider describes an optional interface that an
+// `EngineProvider` can satisfy if the engine does not support the standard
+// `ALTER TABLE ... DROP CONSTRAINT ...` statement for dropping a `UNIQUE`
+// constraint. This is used when upgrading the migrations metadata table.
+type DropConstraintProvider interface {
+	// DropUniqueConstraintSQL returns a statement that drops the `UNIQUE`
+	// constraint named `constraint` from `table`; neither is quoted.
+	DropUniqueConstraintSQL(table, constraint string) string
+}
+
+// TransactionalDDLProvider describes an optional interface that an
+// `EngineProvider` can satisfy to indicate whether DDL statements (e.g.
+// `CREATE TABLE`) can be rolled back as part of a transaction. This is
+// required to apply a batch of migrations in a single transaction (see
+// `OptApplySingleTransaction()`).
+type TransactionalDDLProvider interface {
+	// TransactionalDDL indicates if DDL statements are transactional.
+	TransactionalDDL() bool
+}
+
+// TimeoutProvider describes an optional interface that an `EngineProvider`
+// can satisfy to support per-migration lock and statement timeouts (see
+// `OptLockTimeout()` and `OptStatementTimeout()`).
+type TimeoutProvider interface {
+	// TimeoutSQL produces statements that override the lock and statement
+	// timeouts (a zero timeout is left unchanged) along with statements that
+	// restore the current values (read via `q`) after the migration runs. If
+	// `transactional` is true, the statements will be run in the migration's
+	// transaction, otherwise they will be run on the migration's connection;
+	// `q` is the same transaction or connection.
+	TimeoutSQL(ctx context.Context, q RowQuerier, lockTimeout, statementTimeout time.Duration, transactional bool) (set, reset []string, err error)
+}
+
+// ScopedTimeoutProvider describes an optional interface that a
+// `TimeoutProvider` can satisfy if the statements produced by `TimeoutSQL()`
+// for a transaction are scoped to the transaction (e.g. `SET LOCAL` in
+// PostgreSQL). If a transactional migration fails, the timeouts are then not
+// restored since rolling back the transaction discards them (and the
+// transaction may be aborted, so the statements to restore them would fail).
+type ScopedTimeoutProvider interface {
+	// TransactionScopedTimeouts indicates if the statements that override
+	// the timeouts in a transaction are scoped to the transaction.
+	TransactionScopedTimeouts() bool
+}
+
+// RowQuerier is the subset of `*sql.Tx` and `*sql.Conn` used to read a single
+// row, e.g. the current value of a setting.
+type RowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// RetryableErrorProvider describes an optional interface that an
+// `EngineProvider` can satisfy to identify errors (e.g. a lock timeout or a
+// serialization failure) where a migration can safely be retried.
+type RetryableErrorProvider interface {
+	// IsRetryable indicates if `err` is a transient error.
+	IsRetryable(err error) bool
+}
+
+// EngineNameProvider describes an optional interface that an
+// `EngineProvider` can satisfy to name the database engine, e.g. for
+// structured logging.
+type EngineNameProvider interface {
+	// EngineName returns the name of the database engine, e.g. "postgres".
+	EngineName() string
+}
+
+// Observer describes callbacks that are invoked around migration runs, e.g.
+// to send deploy annotations or to page on failures. An observer is
+// registered on a manager via `OptManagerObserver()`. The `Before*`
+// callbacks can veto a run (or a single migration) by returning an error;
+// the `After*` callbacks and `OnError` are purely informational. Every
+// `BeforeRun` is followed by exactly one `AfterRun` and every
+// `BeforeMigration` is followed by exactly one `AfterMigration` or `OnError`,
+// even if a different observer vetoed. When an observer vetoes, the observers
+// after it are not invoked at all (for the run or the migration).
+type Observer interface {
+	// BeforeRun is invoked (while holding the migration lock) before a batch
+	// of migrations is applied, i.e. by `Up()`, `UpOne()`, `UpTo()` (and the
+	// variants with a report) or `ApplyMigration()`. It is not invoked for a dry run or if there
+	// are no migrations to apply.
+	BeforeRun(ctx context.Context, migrations []Migration) error
+	// AfterRun is invoked after a batch of migrations is applied (or
+	// failed), with a report describing the outcome of each migration.
+	AfterRun(ctx context.Context, report *ApplyReport, err error)
+	// BeforeMigration is invoked before a migration is run. It is not
+	// invoked for a migration that is stamped rather than run.
+	BeforeMigration(ctx context.Context, migration Migration) error
+	// AfterMigration is invoked after a migration was applied.
+	AfterMigration(ctx context.Context, result MigrationResult)
+	// OnError is invoked if a migration fails or is vetoed.
+	OnError(ctx context.Context, migration Migration, err error)
+}
+
+// MetricsCollector describes an optional collector for metrics about
+// migration runs, registered on a manager via `OptManagerMetrics()`. See
+// `TextCollector` for an implementation that uses the Prometheus text
+// exposition format.
+type MetricsCollector interface {
+	// ObserveMigration records the outcome (i.e. `StatusApplied`,
+	// `StatusFailed` or `StatusDirty`) of a migration that was run, along
+	// with the time it took.
+	ObserveMigration(revision string, status MigrationStatus, duration time.Duration)
+	// ObserveLockWait records the time spent waiting to acquire the
+	// migration lock.
+	ObserveLockWait(duration time.Duration)
+	// SetPending records the number of registered migrations that have not
+	// yet been applied.
+	SetPending(count int)
+}
+
 // PrintfReceiver is a generic interface for logging and printing. In cases
 // where a trailing newline is desired (e.g. STDOUT), the type implemented
 // `PrintfReceiver` must add the newline explicitly.
@@ -0,0 +1,304 @@
+package golembic
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sqlFileExtension = ".sql"
+	sqlCommentPrefix = "--"
+)
+
+var (
+	// sqlFilenamePattern matches filenames of the form
+	// `NNNN_<revision>_<slug>.sql`, e.g. `0001_c9b52448285b_create_users_table.sql`.
+	sqlFilenamePattern = regexp.MustCompile(`^([0-9]+)_([^_]+)_(.+)\.sql$`)
+)
+
+// sqlFile represents a `.sql` migration file that has been parsed by
+// `LoadSequenceFromDir()`.
+type sqlFile struct {
+	Filename         string
+	Number           int
+	Revision         string
+	Previous         string
+	Merges           []string
+	Description      string
+	Milestone        bool
+	Baseline         bool
+	Transactional    bool
+	LockTimeout      time.Duration
+	StatementTimeout time.Duration
+	Statement        string
+}
+
+// LoadSequenceFromDir creates a sequence of migrations from a directory of
+// `.sql` files. Each file must be named `NNNN_<revision>_<slug>.sql`, e.g.
+// `0001_c9b52448285b_create_users_table.sql`, where `NNNN` determines the
+// order of the migrations; the numbers must be unique and must not have any
+// gaps. Files without a `.sql` extension are ignored.
+//
+// Each file may start with a header comment block containing `key: value`
+// pairs, for example
+//
+//	-- description: Add index on user emails (concurrently)
+//	-- milestone: false
+//	-- transactional: false
+//	CREATE UNIQUE INDEX CONCURRENTLY ...
+//
+// The supported keys are `revision` (defaults to the revision in the
+// filename), `description` (defaults to the slug with underscores replaced by
+// spaces), `milestone` (defaults to false), `baseline`
+// (defaults to false), `transactional` (defaults to true; if false the
+// migration will be run via `UpConn` rather than `Up`), `previous` (defaults
+// to the revision of the preceding file), `merges` (a comma separated list
+// of additional parents for a merge migration), `lock_timeout` and
+// `statement_timeout` (durations such as `30s`, see `OptLockTimeout()` and
+// `OptStatementTimeout()`). Other comment lines in the header are ignored.
+// The recognised header lines are not executed and are not part of the
+// checksum of the migration, e.g. fixing a typo in the description of an
+// applied migration does not cause `ErrChecksumMismatch`.
+func LoadSequenceFromDir(dir string) (*Migrations, error) {
+	return LoadSequenceFromFS(os.DirFS(dir))
+}
+
+// LoadSequenceFromFS creates a sequence of migrations from the `.sql` files
+// in the root of `fsys`, e.g. an `embed.FS`. See `LoadSequenceFromDir()` for
+// the naming convention and header comment block used by the files.
+func LoadSequenceFromFS(fsys fs.FS) (*Migrations, error) {
+	files, err := readSQLFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations *Migrations
+	previous := ""
+	for _, file := range files {
+		if file.Previous != "" {
+			previous = file.Previous
+		}
+		migration, err := file.migration(previous)
+		if err != nil {
+			return nil, err
+		}
+
+		if migrations == nil {
+			migrations, err = NewSequence(*migration)
+		} else {
+			err = migrations.Register(*migration)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w; filename: %q", err, file.Filename)
+		}
+
+		previous = file.Revision
+	}
+
+	return migrations, nil
+}
+
+// LoadMigrationFromFS creates a single migration from a `.sql` file in `fsys`.
+// See `LoadSequenceFromDir()` for the naming convention and header comment
+// block used by the file. Since the file is read in isolation, the migration
+// only has a parent if the header has a `previous` key.
+func LoadMigrationFromFS(fsys fs.FS, filename string) (*Migration, error) {
+	file, err := readSQLFile(fsys, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.migration(file.Previous)
+}
+
+// migration creates a migration from a parsed `.sql` file, with `previous` as
+// the parent.
+func (sf *sqlFile) migration(previous string) (*Migration, error) {
+	opts := []MigrationOption{
+		OptPrevious(previous),
+		OptMerges(sf.Merges...),
+		OptRevision(sf.Revision),
+		OptDescription(sf.Description),
+		OptMilestone(sf.Milestone),
+		OptBaseline(sf.Baseline),
+		OptLockTimeout(sf.LockTimeout),
+		OptStatementTimeout(sf.StatementTimeout),
+	}
+	if sf.Transactional {
+		opts = append(opts, OptUpFromSQL(sf.Statement))
+	} else {
+		opts = append(opts, OptUpConnFromSQL(sf.Statement))
+	}
+
+	migration, err := NewMigration(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w; filename: %q", err, sf.Filename)
+	}
+
+	return migration, nil
+}
+
+// readSQLFiles reads and parses all `.sql` files in the root of `fsys` and
+// returns them sorted by number. This also validates that the numbers have
+// no duplicates or gaps.
+func readSQLFiles(fsys fs.FS) ([]sqlFile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []sqlFile{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != sqlFileExtension {
+			continue
+		}
+
+		file, err := readSQLFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoMigrations
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Number < files[j].Number
+	})
+
+	for i := 1; i < len(files); i++ {
+		previous := files[i-1]
+		current := files[i]
+		if previous.Number == current.Number {
+			err = fmt.Errorf(
+				"%w; number: %d, filenames: %q and %q",
+				ErrDuplicateNumber, current.Number, previous.Filename, current.Filename,
+			)
+			return nil, err
+		}
+
+		if current.Number != previous.Number+1 {
+			err = fmt.Errorf(
+				"%w; no migration between %q and %q",
+				ErrSequenceGap, previous.Filename, current.Filename,
+			)
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// readSQLFile reads and parses a single `.sql` file in `fsys`.
+func readSQLFile(fsys fs.FS, filename string) (sqlFile, error) {
+	match := sqlFilenamePattern.FindStringSubmatch(filename)
+	if match == nil {
+		err := fmt.Errorf("%w; filename: %q, expected NNNN_<revision>_<slug>.sql", ErrMalformedFilename, filename)
+		return sqlFile{}, err
+	}
+
+	number, err := strconv.Atoi(match[1])
+	if err != nil {
+		err = fmt.Errorf("%w; filename: %q, %v", ErrMalformedFilename, filename, err)
+		return sqlFile{}, err
+	}
+
+	contents, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return sqlFile{}, err
+	}
+
+	file := sqlFile{
+		Filename:      filename,
+		Number:        number,
+		Revision:      match[2],
+		Description:   strings.ReplaceAll(match[3], "_", " "),
+		Transactional: true,
+		Statement:     string(contents),
+	}
+	err = file.parseHeader()
+	if err != nil {
+		return sqlFile{}, err
+	}
+
+	return file, nil
+}
+
+// parseHeader parses the header comment block at the start of the
+// statement, i.e. all lines before the first line that is not a comment. The
+// recognised `key: value` lines are removed from the statement, so that the
+// checksum of the migration only covers the SQL that is executed.
+func (sf *sqlFile) parseHeader() error {
+	body := []string{}
+	inHeader := true
+	for _, raw := range strings.SplitAfter(sf.Statement, "\n") {
+		line := strings.TrimSpace(raw)
+		if !inHeader || !strings.HasPrefix(line, sqlCommentPrefix) {
+			inHeader = false
+			body = append(body, raw)
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, sqlCommentPrefix), ":")
+		if !ok {
+			body = append(body, raw)
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "revision":
+			sf.Revision = value
+		case "description":
+			sf.Description = value
+		case "milestone":
+			sf.Milestone, err = strconv.ParseBool(value)
+		case "baseline":
+			sf.Baseline, err = strconv.ParseBool(value)
+		case "transactional":
+			sf.Transactional, err = strconv.ParseBool(value)
+		case "previous":
+			sf.Previous = value
+		case "merges":
+			sf.Merges = splitRevisions(value)
+		case "lock_timeout":
+			sf.LockTimeout, err = time.ParseDuration(value)
+		case "statement_timeout":
+			sf.StatementTimeout, err = time.ParseDuration(value)
+		default:
+			body = append(body, raw)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w; filename: %q, key: %q, value: %q", ErrMalformedHeader, sf.Filename, key, value)
+		}
+	}
+
+	sf.Statement = strings.Join(body, "")
+	return nil
+}
+
+// splitRevisions splits a comma separated list of revisions, ignoring any
+// whitespace and empty entries.
+func splitRevisions(value string) []string {
+	revisions := []string{}
+	for _, revision := range strings.Split(value, ",") {
+		revision = strings.TrimSpace(revision)
+		if revision != "" {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	return revisions
+}
@@ -0,0 +1,260 @@
+package golembic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestReadSQLFile(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name     string
+		Filename string
+		Contents string
+		Want     sqlFile
+		Err      error
+	}{
+		{
+			Name:     "defaults from filename",
+			Filename: "0001_c9b52448285b_create_users_table.sql",
+			Contents: "CREATE TABLE users (id INTEGER);\n",
+			Want: sqlFile{
+				Number:        1,
+				Revision:      "c9b52448285b",
+				Description:   "create users table",
+				Transactional: true,
+				Statement:     "CREATE TABLE users (id INTEGER);\n",
+			},
+		},
+		{
+			Name:     "header",
+			Filename: "0012_abc_add_index.sql",
+			Contents: "-- revision: def\n" +
+				"-- Description: Add index on user emails\n" +
+				"-- milestone: true\n" +
+				"-- baseline: false\n" +
+				"-- transactional: false\n" +
+				"-- previous: xyz\n" +
+				"-- merges: a, ,b\n" +
+				"-- lock_timeout: 5s\n" +
+				"-- statement_timeout: 1m\n" +
+				"-- A comment that is kept\n" +
+				"-- ticket: ABC-123\n" +
+				"CREATE INDEX users_email ON users (email);\n",
+			Want: sqlFile{
+				Number:           12,
+				Revision:         "def",
+				Previous:         "xyz",
+				Merges:           []string{"a", "b"},
+				Description:      "Add index on user emails",
+				Milestone:        true,
+				LockTimeout:      5 * time.Second,
+				StatementTimeout: time.Minute,
+				Statement: "-- A comment that is kept\n" +
+					"-- ticket: ABC-123\n" +
+					"CREATE INDEX users_email ON users (email);\n",
+			},
+		},
+		{
+			Name:     "header ends at first statement",
+			Filename: "0002_abc_slug.sql",
+			Contents: "-- milestone: true\n" +
+				"SELECT 1;\n" +
+				"-- milestone: false\n",
+			Want: sqlFile{
+				Number:        2,
+				Revision:      "abc",
+				Description:   "slug",
+				Milestone:     true,
+				Transactional: true,
+				Statement:     "SELECT 1;\n-- milestone: false\n",
+			},
+		},
+		{
+			Name:     "missing slug",
+			Filename: "0001_abc.sql",
+			Err:      ErrMalformedFilename,
+		},
+		{
+			Name:     "missing number",
+			Filename: "abc_create_users.sql",
+			Err:      ErrMalformedFilename,
+		},
+		{
+			Name:     "number out of range",
+			Filename: "99999999999999999999_abc_slug.sql",
+			Err:      ErrMalformedFilename,
+		},
+		{
+			Name:     "malformed boolean",
+			Filename: "0001_abc_slug.sql",
+			Contents: "-- milestone: maybe\nSELECT 1;\n",
+			Err:      ErrMalformedHeader,
+		},
+		{
+			Name:     "malformed duration",
+			Filename: "0001_abc_slug.sql",
+			Contents: "-- lock_timeout: 5\nSELECT 1;\n",
+			Err:      ErrMalformedHeader,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			fsys := fstest.MapFS{tc.Filename: {Data: []byte(tc.Contents)}}
+			got, err := readSQLFile(fsys, tc.Filename)
+			if tc.Err != nil {
+				if !errors.Is(err, tc.Err) {
+					t.Fatalf("error = %v, want %v", err, tc.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tc.Want.Filename = tc.Filename
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("readSQLFile() = %#v, want %#v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestLoadSequenceFromFS(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name  string
+		Files fstest.MapFS
+		// Want maps each revision (in order) to its parents, i.e. the
+		// previous revision followed by any merges.
+		Want [][]string
+		Err  error
+	}{
+		{
+			Name: "linear",
+			Files: fstest.MapFS{
+				"0002_b_second.sql":   {Data: []byte("SELECT 2;")},
+				"0001_a_first.sql":    {Data: []byte("SELECT 1;")},
+				"0003_c_third.sql":    {Data: []byte("SELECT 3;")},
+				"README.md":           {Data: []byte("not a migration")},
+				"nested/0004_d_x.sql": {Data: []byte("SELECT 4;")},
+			},
+			Want: [][]string{{"a"}, {"b", "a"}, {"c", "b"}},
+		},
+		{
+			Name: "branch and merge",
+			Files: fstest.MapFS{
+				"0001_a_root.sql":   {Data: []byte("SELECT 1;")},
+				"0002_b_left.sql":   {Data: []byte("SELECT 2;")},
+				"0003_c_right.sql":  {Data: []byte("-- previous: a\nSELECT 3;")},
+				"0004_d_merge.sql":  {Data: []byte("-- merges: b\nSELECT 4;")},
+				"0005_e_follow.sql": {Data: []byte("SELECT 5;")},
+			},
+			Want: [][]string{{"a"}, {"b", "a"}, {"c", "a"}, {"d", "c", "b"}, {"e", "d"}},
+		},
+		{
+			Name:  "empty",
+			Files: fstest.MapFS{"README.md": {Data: []byte("")}},
+			Err:   ErrNoMigrations,
+		},
+		{
+			Name: "gap",
+			Files: fstest.MapFS{
+				"0001_a_first.sql": {Data: []byte("SELECT 1;")},
+				"0003_c_third.sql": {Data: []byte("SELECT 3;")},
+			},
+			Err: ErrSequenceGap,
+		},
+		{
+			Name: "duplicate number",
+			Files: fstest.MapFS{
+				"0001_a_first.sql":  {Data: []byte("SELECT 1;")},
+				"0002_b_second.sql": {Data: []byte("SELECT 2;")},
+				"02_c_other.sql":    {Data: []byte("SELECT 3;")},
+			},
+			Err: ErrDuplicateNumber,
+		},
+		{
+			Name: "duplicate revision",
+			Files: fstest.MapFS{
+				"0001_a_first.sql":  {Data: []byte("SELECT 1;")},
+				"0002_a_second.sql": {Data: []byte("SELECT 2;")},
+			},
+			Err: ErrAlreadyRegistered,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations, err := LoadSequenceFromFS(tc.Files)
+			if tc.Err != nil {
+				if !errors.Is(err, tc.Err) {
+					t.Fatalf("error = %v, want %v", err, tc.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := [][]string{}
+			for _, migration := range migrations.All() {
+				entry := []string{migration.Revision}
+				if migration.Previous != "" {
+					entry = append(entry, migration.Previous)
+				}
+				got = append(got, append(entry, migration.Merges...))
+			}
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("migrations = %v, want %v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationFromFSChecksum(t *testing.T) {
+	t.Parallel()
+
+	base := "-- description: Create users\nCREATE TABLE users (id INTEGER);\n"
+	cases := []struct {
+		Name     string
+		Contents string
+		Same     bool
+	}{
+		{Name: "identical", Contents: base, Same: true},
+		{Name: "description changed", Contents: "-- description: Create the users table\nCREATE TABLE users (id INTEGER);\n", Same: true},
+		{Name: "header key added", Contents: "-- milestone: false\n" + base, Same: true},
+		{Name: "comment added", Contents: "-- Owner: platform\n" + base, Same: false},
+		{Name: "statement changed", Contents: "-- description: Create users\nCREATE TABLE users (id BIGINT);\n", Same: false},
+	}
+
+	filename := "0001_abc_create_users.sql"
+	want, err := LoadMigrationFromFS(fstest.MapFS{filename: {Data: []byte(base)}}, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := LoadMigrationFromFS(fstest.MapFS{filename: {Data: []byte(tc.Contents)}}, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got.Checksum == want.Checksum) != tc.Same {
+				t.Fatalf("checksum = %q, base checksum = %q, want same: %t", got.Checksum, want.Checksum, tc.Same)
+			}
+		})
+	}
+}
@@ -3,27 +3,37 @@ package golembic
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 )
 
 // NOTE: Ensure that
-//   - `Manager.sinceOrAll` satisfies `migrationsFilter`.
+//   - `Manager.pending` satisfies `migrationsFilter`.
 var (
-	_ migrationsFilter = (*Manager)(nil).sinceOrAll
+	_ migrationsFilter = (*Manager)(nil).pending
 )
 
 const (
 	// DefaultMetadataTable is the default name for the table used to store
 	// metadata about migrations.
 	DefaultMetadataTable = "golembic_migrations"
+	// DefaultMigrationLockTimeout is the default timeout to use when waiting
+	// to acquire the migration lock.
+	DefaultMigrationLockTimeout = time.Minute
 )
 
 // NewManager creates a new manager for orchestrating migrations.
 func NewManager(opts ...ManagerOption) (*Manager, error) {
 	m := &Manager{
-		MetadataTable: DefaultMetadataTable,
-		Log:           &stdoutPrintf{},
+		MetadataTable:        DefaultMetadataTable,
+		MigrationLockTimeout: DefaultMigrationLockTimeout,
+		AppliedBy:            defaultAppliedBy(),
+		Hostname:             defaultHostname(),
+		Log:                  &stdoutPrintf{},
 	}
 	for _, opt := range opts {
 		err := opt(m)
@@ -58,8 +68,40 @@ type Manager struct {
 	// and migrations will be applied from scratch (including milestones that
 	// may not come at the end).
 	DevelopmentMode bool
-	// Log is used for printing output
+	// MigrationLockTimeout is the maximum amount of time to wait when
+	// acquiring the migration lock, if `Provider` satisfies `LockProvider`.
+	// A value of zero means waiting indefinitely.
+	MigrationLockTimeout time.Duration
+	// AutoUpgradeMetadataTable is a flag indicating that the migrations
+	// metadata table should be upgraded (if it was created by an older version
+	// of this package) when it is checked by `EnsureMigrationsTable()`, i.e.
+	// by commands that hold the migration lock.
+	AutoUpgradeMetadataTable bool
+	// AppliedBy is the user applying migrations; it is stored in the `applied_by`
+	// column of the migrations metadata table. It defaults to the current
+	// user of the process.
+	AppliedBy string
+	// Hostname is the host applying migrations; it is stored in the `hostname`
+	// column of the migrations metadata table. It defaults to the hostname
+	// reported by the operating system.
+	Hostname string
+	// ApplicationVersion is the version (e.g. a release tag or commit SHA) of
+	// the application applying migrations; it is stored in the `app_version`
+	// column of the migrations metadata table.
+	ApplicationVersion string
+	// Log is used for printing output (unless `Slog` is set).
 	Log PrintfReceiver
+	// Slog is used for structured logging, e.g. with the revision and
+	// duration of a migration as attributes. If set, it takes precedence over
+	// `Log` and all output is sent to it. If not set, the messages of
+	// structured log records are printed via `Log`.
+	Slog *slog.Logger
+	// Observers are notified (in order) before and after migrations are
+	// applied; see `Observer`.
+	Observers []Observer
+	// Metrics collects metrics about migration runs, e.g. the number of
+	// migrations applied. This is optional.
+	Metrics MetricsCollector
 }
 
 // NewConnectionPool creates a new database connection pool and validates that
@@ -111,40 +153,268 @@ func (m *Manager) EnsureConnectionPool(ctx context.Context) (*sql.DB, error) {
 }
 
 // EnsureMigrationsTable checks that the migrations metadata table exists
-// and creates it if not.
+// and creates it if not. If the table exists but was created by an older
+// version of this package, it will be upgraded if `AutoUpgradeMetadataTable`
+// is set; otherwise `ErrMetadataTableOutdated` is returned. This is intended
+// to be invoked while holding the migration lock.
 func (m *Manager) EnsureMigrationsTable(ctx context.Context) error {
-	return CreateMigrationsTable(ctx, m)
+	err := CreateMigrationsTable(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	return m.checkMetadataTableVersion(ctx, m.AutoUpgradeMetadataTable)
+}
+
+// checkMigrationsTable is the form of `EnsureMigrationsTable()` used by
+// commands that do not hold the migration lock (e.g. `Status()`); it never
+// upgrades the migrations metadata table.
+func (m *Manager) checkMigrationsTable(ctx context.Context) error {
+	err := CreateMigrationsTable(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	return m.checkMetadataTableVersion(ctx, false)
+}
+
+// AcquireMigrationLock acquires a cross-process lock that is intended to be
+// held while migrations are applied or rolled back. The lock is held on a
+// dedicated connection from the connection pool and is named after the
+// migrations metadata table. The returned function releases the lock (and the
+// connection) and must be invoked by the caller; the lock is released even if
+// `ctx` has been cancelled by then. If the lock can't be released, the
+// connection is discarded rather than returned to the connection pool.
+//
+// If `Provider` does not satisfy `LockProvider`, no lock is acquired and the
+// returned function does nothing.
+func (m *Manager) AcquireMigrationLock(ctx context.Context) (func() error, error) {
+	lp, ok := m.Provider.(LockProvider)
+	if !ok {
+		return func() error { return nil }, nil
+	}
+
+	pool, err := m.EnsureConnectionPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	err = lp.AcquireLock(ctx, conn, m.MetadataTable, m.MigrationLockTimeout)
+	m.collectLockWait(time.Since(started))
+	if err != nil {
+		// NOTE: The connection is discarded since acquiring the lock may have
+		//       been interrupted part way through, e.g. with session settings
+		//       that were not restored.
+		return nil, maybeWrap(err, discardConn(conn), "failed to discard connection")
+	}
+
+	release := func() error {
+		// NOTE: The lock is released even if `ctx` has been cancelled (e.g.
+		//       if applying migrations timed out); otherwise a session level
+		//       lock would still be held when the connection is returned to
+		//       the pool.
+		err := lp.ReleaseLock(context.WithoutCancel(ctx), conn, m.MetadataTable)
+		if err != nil {
+			return maybeWrap(err, discardConn(conn), "failed to discard connection")
+		}
+
+		return conn.Close()
+	}
+	return release, nil
+}
+
+// ReleaseStaleLock forcibly releases the migration lock, e.g. when the
+// process holding it exited without releasing it. This must only be used
+// once the process holding the lock is confirmed to no longer be running.
+//
+// If `Provider` does not satisfy `StaleLockProvider` (i.e. the lock is
+// released when the process holding it exits), this returns
+// `ErrStaleLockUnsupported`.
+func (m *Manager) ReleaseStaleLock(ctx context.Context) (err error) {
+	slp, ok := m.Provider.(StaleLockProvider)
+	if !ok {
+		err = fmt.Errorf("%w; the lock is released when the process holding it exits", ErrStaleLockUnsupported)
+		return
+	}
+
+	pool, err := m.EnsureConnectionPool(ctx)
+	if err != nil {
+		return
+	}
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		err = maybeWrap(err, conn.Close(), "failed to close connection")
+	}()
+
+	err = slp.ForceReleaseLock(ctx, conn, m.MetadataTable)
+	if err != nil {
+		return
+	}
+
+	m.printf("Released stale migration lock %q", m.MetadataTable)
+	return
+}
+
+// discardConn closes the underlying driver connection of `conn` rather than
+// returning it to the connection pool. This is used when a connection may be
+// in an unknown state, e.g. when it may still hold a session level lock.
+func discardConn(conn *sql.Conn) error {
+	// NOTE: Returning `driver.ErrBadConn` from `Raw()` makes `database/sql`
+	//       close the driver connection (and `conn`) instead of reusing it.
+	err := conn.Raw(func(_ interface{}) error {
+		return driver.ErrBadConn
+	})
+	if errors.Is(err, driver.ErrBadConn) {
+		return nil
+	}
+
+	return err
+}
+
+// withMigrationLock invokes `fn` while holding the migration lock.
+func (m *Manager) withMigrationLock(ctx context.Context, fn func() error) (err error) {
+	release, err := m.AcquireMigrationLock(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		err = maybeWrap(err, release(), "failed to release migration lock")
+	}()
+
+	err = fn()
+	return
 }
 
 // InsertMigration inserts a migration into the migrations metadata table.
+// The `applied_by`, `hostname` and `app_version` columns are populated from
+// the manager and `duration_ms` and `dirty` are populated from the migration.
+// The `merges` column stores the additional parents of a merge migration as a
+// comma separated list. The `serial_id` is one more than the largest stored
+// value, i.e. rows are sorted in the order they were inserted (even if the
+// branches of the sequence are applied in a different order than they were
+// registered).
 func (m *Manager) InsertMigration(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	checksum := nullString(migration.Checksum)
+	appliedBy := nullString(m.AppliedBy)
+	hostname := nullString(m.Hostname)
+	durationMS := migration.applyMetadata.Duration.Milliseconds()
+	appVersion := nullString(m.ApplicationVersion)
 	if migration.Previous == "" {
 		statement := fmt.Sprintf(
-			"INSERT INTO %s (serial_id, revision, previous) VALUES (0, %s, NULL)",
+			"INSERT INTO %s (serial_id, revision, previous, dirty, %s) VALUES (0, %s, NULL, %s, %s, %s, %s, %s, %s)",
 			m.Provider.QuoteIdentifier(m.MetadataTable),
+			auditColumns,
 			m.Provider.QueryParameter(1),
+			m.Provider.QueryParameter(2),
+			m.Provider.QueryParameter(3),
+			m.Provider.QueryParameter(4),
+			m.Provider.QueryParameter(5),
+			m.Provider.QueryParameter(6),
+			m.Provider.QueryParameter(7),
+		)
+		_, err := tx.ExecContext(
+			ctx,
+			statement,
+			migration.Revision, // Parameter 1
+			migration.dirty,    // Parameter 2
+			checksum,           // Parameter 3
+			appliedBy,          // Parameter 4
+			hostname,           // Parameter 5
+			durationMS,         // Parameter 6
+			appVersion,         // Parameter 7
 		)
-		_, err := tx.ExecContext(ctx, statement, migration.Revision)
 		return err
 	}
 
+	serialID, err := m.nextSerialID(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	merges := nullString(strings.Join(migration.Merges, ","))
 	statement := fmt.Sprintf(
-		"INSERT INTO %s (serial_id, revision, previous) VALUES (%s, %s, %s)",
+		"INSERT INTO %s (serial_id, revision, previous, merges, dirty, %s) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)",
 		m.Provider.QuoteIdentifier(m.MetadataTable),
+		auditColumns,
 		m.Provider.QueryParameter(1),
 		m.Provider.QueryParameter(2),
 		m.Provider.QueryParameter(3),
+		m.Provider.QueryParameter(4),
+		m.Provider.QueryParameter(5),
+		m.Provider.QueryParameter(6),
+		m.Provider.QueryParameter(7),
+		m.Provider.QueryParameter(8),
+		m.Provider.QueryParameter(9),
+		m.Provider.QueryParameter(10),
 	)
-	_, err := tx.ExecContext(
+	_, err = tx.ExecContext(
 		ctx,
 		statement,
-		migration.serialID, // Parameter 1
+		serialID,           // Parameter 1
 		migration.Revision, // Parameter 2
 		migration.Previous, // Parameter 3
+		merges,             // Parameter 4
+		migration.dirty,    // Parameter 5
+		checksum,           // Parameter 6
+		appliedBy,          // Parameter 7
+		hostname,           // Parameter 8
+		durationMS,         // Parameter 9
+		appVersion,         // Parameter 10
 	)
 	return err
 }
 
+// nextSerialID determines the `serial_id` for a (non-root) migration that is
+// about to be inserted into the migrations metadata table. The root migration
+// always has a `serial_id` of 0, so this is never less than 1.
+func (m *Manager) nextSerialID(ctx context.Context, tx *sql.Tx) (int, error) {
+	query := fmt.Sprintf(
+		"SELECT COALESCE(MAX(serial_id), 0) + 1 FROM %s",
+		m.Provider.QuoteIdentifier(m.MetadataTable),
+	)
+	rows, err := readAllInt(ctx, tx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	// NOTE: Here we trust that an aggregate query returns exactly one row.
+	return rows[0], nil
+}
+
+// DeleteMigration deletes a migration from the migrations metadata table.
+func (m *Manager) DeleteMigration(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	statement := fmt.Sprintf(
+		"DELETE FROM %s WHERE revision = %s",
+		m.Provider.QuoteIdentifier(m.MetadataTable),
+		m.Provider.QueryParameter(1),
+	)
+	result, err := tx.ExecContext(ctx, statement, migration.Revision)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected != 1 {
+		return fmt.Errorf("%w; revision: %q", ErrNotApplied, migration.Revision)
+	}
+
+	return nil
+}
+
 // NewTx creates a new transaction after ensuring there is an existing
 // connection.
 func (m *Manager) NewTx(ctx context.Context) (*sql.Tx, error) {
@@ -156,14 +426,49 @@ func (m *Manager) NewTx(ctx context.Context) (*sql.Tx, error) {
 	return pool.BeginTx(ctx, nil)
 }
 
-// ApplyMigration creates a transaction that runs the "Up" migration.
-func (m *Manager) ApplyMigration(ctx context.Context, migration Migration) (err error) {
+// ApplyMigration creates a transaction that runs the "Up" migration while
+// holding the migration lock. If the migration fails with a retryable error,
+// it will be retried according to the retry policy (see `OptApplyRetry()`);
+// the other apply options are ignored. The observers on the manager are
+// notified of the run (of a single migration) and any of them may veto it.
+func (m *Manager) ApplyMigration(ctx context.Context, migration Migration, opts ...ApplyOption) error {
+	ac, err := NewApplyConfig(opts...)
+	if err != nil {
+		return err
+	}
+
+	return m.withMigrationLock(ctx, func() error {
+		report := newApplyReport(ac)
+		observers, err := m.beforeRun(ctx, []Migration{migration})
+		if err != nil {
+			report.record(migration, StatusSkipped, time.Time{}, nil)
+		} else {
+			started := time.Now().UTC()
+			err = m.applyObserved(ctx, ac.Retry, migration, nil)
+			report.recordOutcome(migration, started, err)
+		}
+
+		m.collectReport(ctx, report)
+		m.afterRun(ctx, observers, report, err)
+		return err
+	})
+}
+
+// applyMigration creates a transaction that runs the "Up" migration. The
+// migrations in `replaced` will be stamped (i.e. inserted into the migrations
+// metadata table without being run) in the same transaction; this is intended
+// to be used when applying a baseline to a fresh database. A non-transactional
+// migration is handled by `applyMigrationConn()` instead.
+func (m *Manager) applyMigration(ctx context.Context, migration Migration, replaced []Migration) (err error) {
+	if !migration.Transactional() {
+		return m.applyMigrationConn(ctx, migration, replaced)
+	}
+
 	var tx *sql.Tx
 	defer func() {
 		err = txFinalize(tx, err)
 	}()
 
-	m.Log.Printf("Applying %s: %s", migration.Revision, migration.ExtendedDescription())
 	pool, err := m.EnsureConnectionPool(ctx)
 	if err != nil {
 		return
@@ -174,11 +479,89 @@ func (m *Manager) ApplyMigration(ctx context.Context, migration Migration) (err
 		return
 	}
 
-	err = migration.InvokeUp(ctx, pool, tx)
+	err = m.stampReplaced(ctx, tx, migration, replaced)
+	if err != nil {
+		return
+	}
+
+	err = m.invokeUp(ctx, pool, tx, migration)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// applyMigrationConn runs a non-transactional ("UpConn") migration. Since the
+// migration can't be rolled back, it is first recorded (along with the
+// migrations in `replaced`) as "in progress", i.e. dirty, in a separate
+// transaction and then marked as complete once it succeeds. The dirty row is
+// only inserted once the connection for `UpConn` has been acquired and the
+// timeout overrides (if any) have been set, so a failure before `UpConn` is
+// invoked leaves no trace. If `UpConn` fails (or the migration can't be marked
+// as complete), the row is left dirty and `ErrMigrationDirty` is returned;
+// since it can't be known how much of `UpConn` ran, the dirty row must be
+// resolved via `Repair()` before any more migrations can be applied.
+func (m *Manager) applyMigrationConn(ctx context.Context, migration Migration, replaced []Migration) error {
+	pool, err := m.EnsureConnectionPool(ctx)
+	if err != nil {
+		return err
+	}
+
+	marked := false
+	upConn := migration.UpConn
+	migration.UpConn = func(ctx context.Context, conn *sql.Conn) error {
+		err := m.markInProgress(ctx, migration, replaced)
+		if err != nil {
+			return err
+		}
+
+		marked = true
+		return upConn(ctx, conn)
+	}
+
+	ran, err := m.runUp(ctx, pool, nil, migration)
+	if err != nil && !marked {
+		return err
+	}
+	if err != nil {
+		err = fmt.Errorf("%w; revision: %q; %v", ErrMigrationDirty, migration.Revision, err)
+		return err
+	}
+
+	err = m.markComplete(ctx, ran)
+	if err != nil {
+		err = fmt.Errorf(
+			"%w; revision: %q; failed to mark migration as complete: %v",
+			ErrMigrationDirty, migration.Revision, err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// markInProgress creates a transaction that inserts a migration that is
+// about to be run into the migrations metadata table as dirty, stamping the
+// migrations in `replaced` first.
+func (m *Manager) markInProgress(ctx context.Context, migration Migration, replaced []Migration) (err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	err = m.stampReplaced(ctx, tx, migration, replaced)
 	if err != nil {
 		return
 	}
 
+	migration.dirty = true
 	err = m.InsertMigration(ctx, tx, migration)
 	if err != nil {
 		return
@@ -188,20 +571,179 @@ func (m *Manager) ApplyMigration(ctx context.Context, migration Migration) (err
 	return
 }
 
-// filterMigrations applies a filter function that takes the revision of the
-// last applied migration to determine a set of migrations to run.
+// markComplete creates a transaction that marks a dirty migration as
+// complete.
+func (m *Manager) markComplete(ctx context.Context, migration Migration) (err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	err = m.clearDirty(ctx, tx, migration)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// clearDirty clears the `dirty` flag for a migration in the migrations
+// metadata table and stores the time it took to run.
+func (m *Manager) clearDirty(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	statement := fmt.Sprintf(
+		"UPDATE %s SET dirty = %s, duration_ms = %s WHERE revision = %s",
+		m.Provider.QuoteIdentifier(m.MetadataTable),
+		m.Provider.QueryParameter(1),
+		m.Provider.QueryParameter(2),
+		m.Provider.QueryParameter(3),
+	)
+	durationMS := migration.applyMetadata.Duration.Milliseconds()
+	result, err := tx.ExecContext(ctx, statement, false, durationMS, migration.Revision)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected != 1 {
+		return fmt.Errorf("%w; revision: %q", ErrNotApplied, migration.Revision)
+	}
+
+	return nil
+}
+
+// stampReplaced inserts the migrations replaced by a baseline into the
+// migrations metadata table without running them.
+func (m *Manager) stampReplaced(ctx context.Context, tx *sql.Tx, baseline Migration, replaced []Migration) error {
+	for _, stamped := range replaced {
+		m.printf(
+			"Stamping %s: %s (replaced by baseline %s)",
+			stamped.Revision, stamped.ExtendedDescription(), baseline.Revision,
+		)
+		err := m.InsertMigration(ctx, tx, stamped)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// invokeUp runs the "Up" migration and inserts it into the migrations
+// metadata table (along with the time it took to run), using an existing
+// transaction.
+func (m *Manager) invokeUp(ctx context.Context, pool *sql.DB, tx *sql.Tx, migration Migration) error {
+	migration, err := m.runUp(ctx, pool, tx, migration)
+	if err != nil {
+		return err
+	}
+
+	return m.InsertMigration(ctx, tx, migration)
+}
+
+// runUp runs the "Up" migration (with the migration's timeout overrides, if
+// any) and returns the migration along with the time it took to run. For a
+// non-transactional migration, `tx` is not used and may be `nil`.
+func (m *Manager) runUp(ctx context.Context, pool *sql.DB, tx *sql.Tx, migration Migration) (Migration, error) {
+	message := fmt.Sprintf("Applying %s: %s", migration.Revision, migration.ExtendedDescription())
+	m.logMigration(ctx, slog.LevelInfo, message, migration)
+
+	timed, err := m.withTimeouts(migration)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	started := time.Now()
+	err = timed.InvokeUp(ctx, pool, tx)
+	if err != nil {
+		return Migration{}, err
+	}
+	migration.applyMetadata.Duration = time.Since(started)
+
+	return migration, nil
+}
+
+// RollbackMigration creates a transaction that runs the "Down" migration.
+func (m *Manager) RollbackMigration(ctx context.Context, migration Migration) (err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	m.printf("Rolling back %s: %s", migration.Revision, migration.ExtendedDescription())
+	pool, err := m.EnsureConnectionPool(ctx)
+	if err != nil {
+		return
+	}
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	err = migration.InvokeDown(ctx, pool, tx)
+	if err != nil {
+		return
+	}
+
+	err = m.DeleteMigration(ctx, tx, migration)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// filterMigrations applies a filter function that takes the revisions of the
+// applied migrations to determine a set of migrations to run.
 func (m *Manager) filterMigrations(ctx context.Context, filter migrationsFilter, verifyHistory bool) (int, []Migration, error) {
-	err := m.EnsureMigrationsTable(ctx)
+	err := m.Sequence.Validate()
 	if err != nil {
 		return 0, nil, err
 	}
 
-	latest, _, err := m.latestMaybeVerify(ctx, verifyHistory)
+	err = m.validateHeads(ctx)
 	if err != nil {
 		return 0, nil, err
 	}
 
-	pastMigrationCount, migrations, err := filter(latest)
+	err = m.EnsureMigrationsTable(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = m.ensureClean(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	history, err := m.storedHistory(ctx, verifyHistory)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	applied, err := m.appliedRevisions(history)
+	if err != nil {
+		return 0, nil, err
+	}
+	m.collectPending(applied)
+
+	latest := ""
+	if len(history) > 0 {
+		latest = history[len(history)-1].Revision
+	}
+
+	pastMigrationCount, migrations, err := filter(applied)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -210,19 +752,65 @@ func (m *Manager) filterMigrations(ctx context.Context, filter migrationsFilter,
 		format := "No migrations to run; latest revision: %s"
 
 		// Add `milestoneSuffix`, if we can detect `latest` is a milestone.
+		milestone := false
 		migration := m.Sequence.Get(latest)
 		if migration != nil && migration.Milestone {
 			format += milestoneSuffix
+			milestone = true
 		}
 
-		m.Log.Printf(format, latest)
+		m.logger().LogAttrs(
+			ctx, slog.LevelInfo, fmt.Sprintf(format, latest),
+			slog.String(attrRevision, latest),
+			slog.Bool(attrMilestone, milestone),
+			slog.String(attrEngine, m.engineName()),
+			slog.Int(attrPending, 0),
+		)
 		return pastMigrationCount, nil, nil
 	}
 
+	m.logger().LogAttrs(
+		ctx, slog.LevelDebug, fmt.Sprintf("Found %d migration(s) to run", len(migrations)),
+		slog.String(attrRevision, latest),
+		slog.String(attrEngine, m.engineName()),
+		slog.Int(attrPending, len(migrations)),
+	)
 	return pastMigrationCount, migrations, nil
 }
 
-func (m *Manager) validateMilestones(pastMigrationCount int, migrations []Migration) error {
+// validateHeads makes sure the sequence has not diverged into multiple heads;
+// if it has, a merge migration must be registered before migrations can be
+// applied.
+func (m *Manager) validateHeads(ctx context.Context) error {
+	err := multipleHeadsError(m.Sequence.Heads())
+	if err == nil {
+		return nil
+	}
+
+	// In development mode, log the error message but don't return an error.
+	if m.DevelopmentMode {
+		m.ignoreInDevelopmentMode(ctx, err)
+		return nil
+	}
+
+	return err
+}
+
+// multipleHeadsError returns `ErrMultipleHeads` (with the revisions of each
+// head) if there is more than one head.
+func multipleHeadsError(heads []Migration) error {
+	if len(heads) < 2 {
+		return nil
+	}
+
+	revisions := make([]string, len(heads))
+	for i, head := range heads {
+		revisions[i] = head.Revision
+	}
+	return fmt.Errorf("%w; heads: %s", ErrMultipleHeads, strings.Join(revisions, ", "))
+}
+
+func (m *Manager) validateMilestones(ctx context.Context, pastMigrationCount int, migrations []Migration) error {
 	// Early exit if no migrations have been run yet. This **assumes** that the
 	// database is being brought up from scratch.
 	if pastMigrationCount == 0 {
@@ -244,8 +832,12 @@ func (m *Manager) validateMilestones(pastMigrationCount int, migrations []Migrat
 
 		// In development mode, log the error message but don't return an error.
 		if m.DevelopmentMode {
-			m.Log.Printf("Ignoring error in development mode")
-			m.Log.Printf("  %s", err)
+			m.ignoreInDevelopmentMode(
+				ctx, err,
+				slog.String(attrRevision, migration.Revision),
+				slog.String(attrDescription, migration.Description),
+				slog.Bool(attrMilestone, migration.Milestone),
+			)
 			continue
 		}
 
@@ -255,53 +847,206 @@ func (m *Manager) validateMilestones(pastMigrationCount int, migrations []Migrat
 	return nil
 }
 
-// Up applies all migrations that have not yet been applied.
-func (m *Manager) Up(ctx context.Context, opts ...ApplyOption) error {
-	ac, err := NewApplyConfig(opts...)
-	if err != nil {
-		return err
+// baselineStamped determines which of `migrations` should be stamped rather
+// than applied because of a baseline. For a fresh database (i.e. when no
+// migrations have been run yet), the last baseline will be applied and every
+// migration before it will be stamped. For a database with existing history,
+// every baseline will be stamped since the migrations it replaces have been
+// (or will be) applied.
+func baselineStamped(pastMigrationCount int, migrations []Migration) []bool {
+	last := -1
+	if pastMigrationCount == 0 {
+		for i, migration := range migrations {
+			if migration.Baseline {
+				last = i
+			}
+		}
 	}
 
-	pastMigrationCount, migrations, err := m.filterMigrations(ctx, m.sinceOrAll, ac.VerifyHistory)
-	if err != nil {
-		return err
+	stamped := make([]bool, len(migrations))
+	for i, migration := range migrations {
+		stamped[i] = i < last || (i > last && migration.Baseline)
 	}
+	return stamped
+}
 
-	if migrations == nil {
+// describePlan displays the migrations that would be applied (or stamped),
+// without applying them. This is intended to be used for dry runs.
+func (m *Manager) describePlan(migrations []Migration, stamped []bool) {
+	m.printf("Dry run; %d migration(s) would be applied", len(migrations))
+	for i, migration := range migrations {
+		if stamped[i] {
+			m.printf("Would stamp %s: %s", migration.Revision, migration.ExtendedDescription())
+			continue
+		}
+		m.printf("Would apply %s: %s", migration.Revision, migration.ExtendedDescription())
+	}
+}
+
+// applyMigrations applies migrations (in order) and records the outcome of
+// each in `report`. If a migration fails, the remaining migrations will be
+// recorded as skipped. For a dry run, the migrations will be displayed and
+// recorded as planned, but not applied. Migrations that are replaced by a
+// baseline (see `baselineStamped()`) are stamped rather than applied. The
+// observers on the manager are notified before and after the run (but not
+// for a dry run) and any of them may veto it.
+func (m *Manager) applyMigrations(ctx context.Context, ac *ApplyConfig, pastMigrationCount int, migrations []Migration, report *ApplyReport) error {
+	stamped := baselineStamped(pastMigrationCount, migrations)
+	if ac.SingleTransaction {
+		err := m.checkSingleTransaction(migrations, stamped)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ac.DryRun {
+		m.describePlan(migrations, stamped)
+		for _, migration := range migrations {
+			report.record(migration, StatusPlanned, time.Time{}, nil)
+		}
 		return nil
 	}
 
-	err = m.validateMilestones(pastMigrationCount, migrations)
+	observers, err := m.beforeRun(ctx, migrations)
 	if err != nil {
-		return err
+		for _, migration := range migrations {
+			report.record(migration, StatusSkipped, time.Time{}, nil)
+		}
+	} else if ac.SingleTransaction {
+		err = m.applySingleTransactionWithRetry(ctx, ac.Retry, migrations, stamped, report)
+	} else {
+		err = m.applyEach(ctx, ac, migrations, stamped, report)
 	}
 
-	for _, migration := range migrations {
-		err = m.ApplyMigration(ctx, migration)
+	m.collectReport(ctx, report)
+	m.afterRun(ctx, observers, report, err)
+	return err
+}
+
+// applyEach applies (or stamps) migrations (in order), each in a separate
+// transaction, and records the outcome of each in `report`.
+func (m *Manager) applyEach(ctx context.Context, ac *ApplyConfig, migrations []Migration, stamped []bool, report *ApplyReport) error {
+	replaced := []Migration{}
+	for i, migration := range migrations {
+		// Stamped migrations that come before a baseline are deferred so
+		// they can be stamped in the same transaction as the baseline.
+		if stamped[i] && !migration.Baseline {
+			replaced = append(replaced, migration)
+			continue
+		}
+
+		started := time.Now().UTC()
+		var err error
+		if stamped[i] {
+			err = m.stampMigrations(ctx, []Migration{migration})
+		} else {
+			err = m.applyObserved(ctx, ac.Retry, migration, replaced)
+		}
 		if err != nil {
+			for _, r := range replaced {
+				report.record(r, StatusSkipped, time.Time{}, nil)
+			}
+			report.recordOutcome(migration, started, err)
+			for _, skipped := range migrations[i+1:] {
+				report.record(skipped, StatusSkipped, time.Time{}, nil)
+			}
 			return err
 		}
+
+		for _, r := range replaced {
+			report.record(r, StatusStamped, time.Time{}, nil)
+		}
+		replaced = nil
+		if stamped[i] {
+			report.record(migration, StatusStamped, started, nil)
+		} else {
+			report.record(migration, StatusApplied, started, nil)
+		}
 	}
 
 	return nil
 }
 
-func (m *Manager) sinceOrAll(revision string) (int, []Migration, error) {
-	if revision == "" {
-		return 0, m.Sequence.All(), nil
+// withReport invokes an "up" function while holding the migration lock and
+// produces a report of the outcome. The report is returned even if there
+// is an error.
+func (m *Manager) withReport(ctx context.Context, ac *ApplyConfig, fn func(context.Context, *ApplyConfig, *ApplyReport) error) (*ApplyReport, error) {
+	report := newApplyReport(ac)
+	err := m.withMigrationLock(ctx, func() error {
+		return fn(ctx, ac, report)
+	})
+	report.finish()
+	return report, err
+}
+
+// Up applies all migrations that have not yet been applied.
+func (m *Manager) Up(ctx context.Context, opts ...ApplyOption) error {
+	_, err := m.UpWithReport(ctx, opts...)
+	return err
+}
+
+// UpWithReport applies all migrations that have not yet been applied and
+// returns a report describing the outcome of each migration.
+func (m *Manager) UpWithReport(ctx context.Context, opts ...ApplyOption) (*ApplyReport, error) {
+	ac, err := NewApplyConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.withReport(ctx, ac, m.up)
+}
+
+// up applies all migrations that have not yet been applied. It is expected
+// to be invoked while holding the migration lock.
+func (m *Manager) up(ctx context.Context, ac *ApplyConfig, report *ApplyReport) error {
+	pastMigrationCount, migrations, err := m.filterMigrations(ctx, m.pending, ac.VerifyHistory)
+	if err != nil {
+		return err
+	}
+
+	if migrations == nil {
+		return nil
+	}
+
+	err = m.validateMilestones(ctx, pastMigrationCount, migrations)
+	if err != nil {
+		return err
 	}
 
-	return m.Sequence.Since(revision)
+	return m.applyMigrations(ctx, ac, pastMigrationCount, migrations, report)
 }
 
-// UpOne applies the **next** migration that has yet been applied, if any.
+// pending determines the migrations that have not been applied, i.e. the
+// registered migrations minus the `applied` revisions.
+func (m *Manager) pending(applied []string) (int, []Migration, error) {
+	return len(applied), m.Sequence.Pending(applied), nil
+}
+
+// UpOne applies the **next** migration that has yet been applied, if any. On
+// a fresh database with a baseline, the next migration is the (last) baseline
+// and the migrations it replaces are stamped.
 func (m *Manager) UpOne(ctx context.Context, opts ...ApplyOption) error {
+	_, err := m.UpOneWithReport(ctx, opts...)
+	return err
+}
+
+// UpOneWithReport applies the **next** migration that has yet been applied,
+// if any, and returns a report describing the outcome. On a fresh database
+// with a baseline, the next migration is the (last) baseline and the
+// migrations it replaces are stamped.
+func (m *Manager) UpOneWithReport(ctx context.Context, opts ...ApplyOption) (*ApplyReport, error) {
 	ac, err := NewApplyConfig(opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, migrations, err := m.filterMigrations(ctx, m.sinceOrAll, ac.VerifyHistory)
+	return m.withReport(ctx, ac, m.upOne)
+}
+
+// upOne applies the **next** migration that has yet been applied, if any. It
+// is expected to be invoked while holding the migration lock.
+func (m *Manager) upOne(ctx context.Context, ac *ApplyConfig, report *ApplyReport) error {
+	pastMigrationCount, migrations, err := m.filterMigrations(ctx, m.pending, ac.VerifyHistory)
 	if err != nil {
 		return err
 	}
@@ -310,21 +1055,54 @@ func (m *Manager) UpOne(ctx context.Context, opts ...ApplyOption) error {
 		return nil
 	}
 
-	migration := migrations[0]
-	return m.ApplyMigration(ctx, migration)
+	return m.applyMigrations(ctx, ac, pastMigrationCount, nextStep(pastMigrationCount, migrations), report)
+}
+
+// nextStep determines the migrations applied by `upOne()`, i.e. the next
+// pending migration. For a fresh database with a baseline, the next step is
+// the last baseline along with every migration before it (which will be
+// stamped, see `baselineStamped()`); this way the migrations replaced by the
+// baseline are never run.
+func nextStep(pastMigrationCount int, migrations []Migration) []Migration {
+	if pastMigrationCount == 0 {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if migrations[i].Baseline {
+				return migrations[:i+1]
+			}
+		}
+	}
+
+	return migrations[:1]
 }
 
 // UpTo applies all migrations that have yet to be applied up to (and
 // including) a revision, if any. This expects the `ApplyConfig` revision to
 // be set in `opts`.
 func (m *Manager) UpTo(ctx context.Context, opts ...ApplyOption) error {
+	_, err := m.UpToWithReport(ctx, opts...)
+	return err
+}
+
+// UpToWithReport applies all migrations that have yet to be applied up to
+// (and including) a revision, if any, and returns a report describing the
+// outcome of each migration. This expects the `ApplyConfig` revision to be
+// set in `opts`.
+func (m *Manager) UpToWithReport(ctx context.Context, opts ...ApplyOption) (*ApplyReport, error) {
 	ac, err := NewApplyConfig(opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var filter migrationsFilter = func(latest string) (int, []Migration, error) {
-		return m.betweenOrUntil(latest, ac.Revision)
+	return m.withReport(ctx, ac, m.upTo)
+}
+
+// upTo applies all migrations that have yet to be applied up to (and
+// including) a revision, if any. It is expected to be invoked while holding
+// the migration lock.
+func (m *Manager) upTo(ctx context.Context, ac *ApplyConfig, report *ApplyReport) error {
+	var filter migrationsFilter = func(applied []string) (int, []Migration, error) {
+		migrations, err := m.Sequence.PendingUntil(applied, ac.Revision)
+		return len(applied), migrations, err
 	}
 
 	pastMigrationCount, migrations, err := m.filterMigrations(ctx, filter, ac.VerifyHistory)
@@ -336,13 +1114,110 @@ func (m *Manager) UpTo(ctx context.Context, opts ...ApplyOption) error {
 		return nil
 	}
 
-	err = m.validateMilestones(pastMigrationCount, migrations)
+	err = m.validateMilestones(ctx, pastMigrationCount, migrations)
+	if err != nil {
+		return err
+	}
+
+	return m.applyMigrations(ctx, ac, pastMigrationCount, migrations, report)
+}
+
+// DownOne rolls back the **most recently** applied migration, if any.
+func (m *Manager) DownOne(ctx context.Context, opts ...ApplyOption) error {
+	ac, err := NewApplyConfig(opts...)
+	if err != nil {
+		return err
+	}
+
+	return m.withMigrationLock(ctx, func() error {
+		return m.downOne(ctx, ac)
+	})
+}
+
+// downOne rolls back the **most recently** applied migration, if any. It is
+// expected to be invoked while holding the migration lock.
+func (m *Manager) downOne(ctx context.Context, ac *ApplyConfig) error {
+	applied, err := m.appliedMigrations(ctx, ac.VerifyHistory)
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		m.printf("No migrations to roll back; no migrations have been run")
+		return nil
+	}
+
+	migration := applied[len(applied)-1]
+	err = migration.checkDown()
+	if err != nil {
+		return err
+	}
+
+	return m.RollbackMigration(ctx, migration)
+}
+
+// DownTo rolls back all applied migrations that occur **after** a revision,
+// if any. The migration for the revision itself will remain applied. This
+// expects the `ApplyConfig` revision to be set in `opts`.
+func (m *Manager) DownTo(ctx context.Context, opts ...ApplyOption) error {
+	ac, err := NewApplyConfig(opts...)
+	if err != nil {
+		return err
+	}
+
+	return m.withMigrationLock(ctx, func() error {
+		return m.downTo(ctx, ac)
+	})
+}
+
+// downTo rolls back all applied migrations that occur **after** a revision,
+// if any. It is expected to be invoked while holding the migration lock.
+func (m *Manager) downTo(ctx context.Context, ac *ApplyConfig) error {
+	applied, err := m.appliedMigrations(ctx, ac.VerifyHistory)
+	if err != nil {
+		return err
+	}
+
+	remaining := -1
+	for i, migration := range applied {
+		if migration.Revision == ac.Revision {
+			remaining = i + 1
+			break
+		}
+	}
+
+	if remaining == -1 {
+		err = fmt.Errorf("%w; revision: %q", ErrNotApplied, ac.Revision)
+		return err
+	}
+
+	if remaining == len(applied) {
+		m.printf("No migrations to roll back; latest revision: %s", ac.Revision)
+		return nil
+	}
+
+	// Roll back in the reverse of the order the migrations were applied.
+	migrations := []Migration{}
+	for i := len(applied) - 1; i >= remaining; i-- {
+		migrations = append(migrations, applied[i])
+	}
+
+	err = m.validateMilestones(ctx, remaining, migrations)
 	if err != nil {
 		return err
 	}
 
+	// Make sure every migration can be rolled back **before** rolling back
+	// any of them.
+	for _, migration := range migrations {
+		err = migration.checkDown()
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, migration := range migrations {
-		err = m.ApplyMigration(ctx, migration)
+		err = m.RollbackMigration(ctx, migration)
 		if err != nil {
 			return err
 		}
@@ -351,20 +1226,77 @@ func (m *Manager) UpTo(ctx context.Context, opts ...ApplyOption) error {
 	return nil
 }
 
-func (m *Manager) betweenOrUntil(latest string, revision string) (int, []Migration, error) {
-	if latest == "" {
-		return m.Sequence.Until(revision)
+// appliedMigrations determines the (registered) migrations that have already
+// been applied, in the order they were applied.
+func (m *Manager) appliedMigrations(ctx context.Context, verifyHistory bool) ([]Migration, error) {
+	err := m.EnsureMigrationsTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.ensureClean(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := m.storedHistory(ctx, verifyHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []Migration{}
+	for _, stored := range history {
+		migration := m.Sequence.Get(stored.Revision)
+		if migration == nil {
+			err = fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, stored.Revision)
+			return nil, err
+		}
+		applied = append(applied, *migration)
+	}
+
+	return applied, nil
+}
+
+// appliedRevisions extracts the revisions from the rows in the migrations
+// metadata table, making sure each one is registered.
+func (m *Manager) appliedRevisions(history []Migration) ([]string, error) {
+	applied := []string{}
+	for _, stored := range history {
+		if m.Sequence.Get(stored.Revision) == nil {
+			err := fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, stored.Revision)
+			return nil, err
+		}
+		applied = append(applied, stored.Revision)
 	}
 
-	return m.Sequence.Between(latest, revision)
+	return applied, nil
 }
 
 // Latest determines the revision and timestamp of the most recently applied
 // migration.
+// If the migrations metadata table is outdated, `ErrMetadataTableOutdated` is
+// returned.
 //
 // NOTE: This assumes, but does not check, that the migrations metadata table
 // exists.
 func (m *Manager) Latest(ctx context.Context) (revision string, createdAt time.Time, err error) {
+	stored, err := m.latestStored(ctx)
+	if err != nil {
+		return
+	}
+
+	revision = stored.Revision
+	createdAt = stored.createdAt
+	return
+}
+
+// latestStored reads the row for the most recently applied migration from
+// the migrations metadata table. If no migrations have been applied, the
+// returned migration will have an empty revision.
+//
+// NOTE: This assumes, but does not check, that the migrations metadata table
+// exists.
+func (m *Manager) latestStored(ctx context.Context) (stored Migration, err error) {
 	var tx *sql.Tx
 	defer func() {
 		err = txFinalize(tx, err)
@@ -375,8 +1307,14 @@ func (m *Manager) Latest(ctx context.Context) (revision string, createdAt time.T
 		return
 	}
 
+	err = m.requireLatestMetadataTableVersion(ctx, tx)
+	if err != nil {
+		return
+	}
+
 	query := fmt.Sprintf(
-		"SELECT revision, previous, created_at FROM %s ORDER BY serial_id DESC LIMIT 1",
+		"SELECT %s FROM %s ORDER BY serial_id DESC LIMIT 1",
+		metadataColumns,
 		m.Provider.QuoteIdentifier(m.MetadataTable),
 	)
 	tc := m.Provider.TimestampColumn()
@@ -391,16 +1329,16 @@ func (m *Manager) Latest(ctx context.Context) (revision string, createdAt time.T
 
 	// NOTE: Here we trust that the query is sufficient to guarantee that
 	//       `len(rows) == 1`.
-	revision = rows[0].Revision
-	createdAt = rows[0].createdAt
+	stored = rows[0]
 	return
 }
 
-// latestMaybeVerify determines the latest applied migration and verifies all of the
-// migration history if `verifyHistory` is true.
-func (m *Manager) latestMaybeVerify(ctx context.Context, verifyHistory bool) (revision string, createdAt time.Time, err error) {
+// latestMaybeVerify determines the latest applied migration (as stored in
+// the migrations metadata table) and verifies all of the migration history if
+// `verifyHistory` is true.
+func (m *Manager) latestMaybeVerify(ctx context.Context, verifyHistory bool) (stored Migration, err error) {
 	if !verifyHistory {
-		revision, createdAt, err = m.Latest(ctx)
+		stored, err = m.latestStored(ctx)
 		return
 	}
 
@@ -423,12 +1361,53 @@ func (m *Manager) latestMaybeVerify(ctx context.Context, verifyHistory bool) (re
 		return
 	}
 
-	revision = history[len(history)-1].Revision
-	createdAt = history[len(history)-1].createdAt
+	stored = history[len(history)-1]
+	err = tx.Commit()
+	return
+}
+
+// storedHistory reads every row from the migrations metadata table (in the
+// order they were applied) and verifies all of the migration history if
+// `verifyHistory` is true.
+func (m *Manager) storedHistory(ctx context.Context, verifyHistory bool) (history []Migration, err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	if verifyHistory {
+		history, _, err = m.verifyHistory(ctx, tx)
+	} else {
+		history, err = m.readHistory(ctx, tx)
+	}
+	if err != nil {
+		return
+	}
+
 	err = tx.Commit()
 	return
 }
 
+// readHistory reads every row from the migrations metadata table, in the
+// order they were applied.
+//
+// NOTE: This assumes, but does not check, that the migrations metadata table
+// exists.
+func (m *Manager) readHistory(ctx context.Context, tx *sql.Tx) ([]Migration, error) {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s ORDER BY serial_id ASC",
+		metadataColumns,
+		m.Provider.QuoteIdentifier(m.MetadataTable),
+	)
+	tc := m.Provider.TimestampColumn()
+	return readAllMigration(ctx, tx, query, tc)
+}
+
 // GetVersion returns the migration that corresponds to the version that was
 // most recently applied.
 func (m *Manager) GetVersion(ctx context.Context, opts ...ApplyOption) (*Migration, error) {
@@ -437,103 +1416,118 @@ func (m *Manager) GetVersion(ctx context.Context, opts ...ApplyOption) (*Migrati
 		return nil, err
 	}
 
-	err = m.EnsureMigrationsTable(ctx)
+	err = m.checkMigrationsTable(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	revision, createdAt, err := m.latestMaybeVerify(ctx, ac.VerifyHistory)
+	stored, err := m.latestMaybeVerify(ctx, ac.VerifyHistory)
 	if err != nil {
 		return nil, err
 	}
 
-	if revision == "" {
+	if stored.Revision == "" {
 		return nil, nil
 	}
 
-	migration := m.Sequence.Get(revision)
+	migration := m.Sequence.Get(stored.Revision)
 	if migration == nil {
-		err = fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, revision)
+		err = fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, stored.Revision)
 		return nil, err
 	}
 
 	withCreated := &Migration{
-		Previous:    migration.Previous,
-		Revision:    migration.Revision,
-		Description: migration.Description,
-		createdAt:   createdAt,
+		Previous:      migration.Previous,
+		Revision:      migration.Revision,
+		Description:   migration.Description,
+		Milestone:     migration.Milestone,
+		createdAt:     stored.createdAt,
+		applyMetadata: stored.applyMetadata,
 	}
 	return withCreated, nil
 }
 
 // Verify checks that the rows in the migrations metadata table match the
-// sequence.
-func (m *Manager) Verify(ctx context.Context) (err error) {
-	var tx *sql.Tx
-	defer func() {
-		err = txFinalize(tx, err)
-	}()
-
-	err = m.EnsureMigrationsTable(ctx)
-	if err != nil {
-		return
-	}
-
-	tx, err = m.NewTx(ctx)
+// sequence. If a migration is dirty, `ErrMigrationDirty` is returned (after
+// displaying every migration).
+func (m *Manager) Verify(ctx context.Context) error {
+	entries, err := m.Status(ctx)
 	if err != nil {
-		return
-	}
-
-	history, registered, err := m.verifyHistory(ctx, tx)
-	if err != nil {
-		return
+		return err
 	}
 
-	for i, migration := range registered {
-		description := migration.ExtendedDescription()
-		if i < len(history) {
-			applied := history[i]
-			m.Log.Printf(
+	for i, entry := range entries {
+		description := entry.ExtendedDescription()
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String(attrRevision, entry.Revision),
+			slog.String(attrDescription, entry.Description),
+			slog.Bool(attrMilestone, entry.Milestone),
+			slog.String(attrEngine, m.engineName()),
+		}
+		var message string
+		if entry.Dirty {
+			level = slog.LevelWarn
+			message = fmt.Sprintf(
+				"%d | %s | %s (dirty; started %s)",
+				i, entry.Revision, description, appliedSummary(entry.AppliedAt, entry.ApplyMetadata),
+			)
+			attrs = append(attrs, slog.String(attrOutcome, "dirty"))
+		} else if entry.Applied {
+			message = fmt.Sprintf(
 				"%d | %s | %s (applied %s)",
-				i, migration.Revision, description, applied.createdAt,
+				i, entry.Revision, description, appliedSummary(entry.AppliedAt, entry.ApplyMetadata),
+			)
+			attrs = append(
+				attrs,
+				slog.String(attrOutcome, "applied"),
+				slog.Duration(attrDuration, entry.ApplyMetadata.Duration),
 			)
 		} else {
-			m.Log.Printf(
+			message = fmt.Sprintf(
 				"%d | %s | %s (not yet applied)",
-				i, migration.Revision, description,
+				i, entry.Revision, description,
 			)
+			attrs = append(attrs, slog.String(attrOutcome, "pending"))
 		}
+
+		m.logger().LogAttrs(ctx, level, message, attrs...)
 	}
 
-	return
+	return DirtyStatusError(entries)
 }
 
 // verifyHistory retrieves a full history of migrations and compares it against
-// the sequence of registered migrations. If they match (up to the end of the
-// history, the registered sequence can be longer), this will return with no
-// error and include slices of the history and the registered migrations.
+// the sequence of registered migrations. Every stored migration must be
+// registered (with the same parents and checksum) and must have been applied
+// after all of its parents; the registered sequence can have more migrations,
+// e.g. pending migrations or a branch that has not been applied. If they
+// match, this will return with no error and include slices of the history and
+// the registered migrations.
 func (m *Manager) verifyHistory(ctx context.Context, tx *sql.Tx) (history, registered []Migration, err error) {
-	query := fmt.Sprintf(
-		"SELECT revision, previous, created_at FROM %s ORDER BY serial_id ASC",
-		m.Provider.QuoteIdentifier(m.MetadataTable),
-	)
-	tc := m.Provider.TimestampColumn()
-	history, err = readAllMigration(ctx, tx, query, tc)
+	history, err = m.readHistory(ctx, tx)
 	if err != nil {
 		return
 	}
 
 	registered = m.Sequence.All()
-	if len(history) > len(registered) {
-		err = fmt.Errorf(
-			"%w; sequence has %d migrations but %d are stored in the table",
-			ErrMigrationMismatch, len(registered), len(history),
-		)
-		return
+	index := map[string]int{}
+	for i, migration := range registered {
+		index[migration.Revision] = i
 	}
 
+	applied := map[string]bool{}
 	for i, row := range history {
-		expected := registered[i]
+		position, ok := index[row.Revision]
+		if !ok {
+			err = fmt.Errorf(
+				"%w; stored migration %d: %q is not registered in the sequence",
+				ErrMigrationMismatch, i, row.Compact(),
+			)
+			return
+		}
+
+		expected := registered[position]
 		if !row.Like(expected) {
 			err = fmt.Errorf(
 				"%w; stored migration %d: %q does not match migration %q in sequence",
@@ -541,6 +1535,27 @@ func (m *Manager) verifyHistory(ctx context.Context, tx *sql.Tx) (history, regis
 			)
 			return
 		}
+
+		for _, parent := range row.Parents() {
+			if !applied[parent] {
+				err = fmt.Errorf(
+					"%w; stored migration %d: %q was applied before its parent %q",
+					ErrMigrationMismatch, i, row.Revision, parent,
+				)
+				return
+			}
+		}
+		applied[row.Revision] = true
+
+		// NOTE: Checksums are optional, e.g. a migration may be a Go function
+		//       or may have been applied before checksums were stored.
+		if row.Checksum != "" && expected.Checksum != "" && row.Checksum != expected.Checksum {
+			err = fmt.Errorf(
+				"%w; stored migration %d: %q has checksum %q but migration in sequence has checksum %q",
+				ErrChecksumMismatch, i, row.Revision, row.Checksum, expected.Checksum,
+			)
+			return
+		}
 	}
 
 	return
@@ -548,10 +1563,22 @@ func (m *Manager) verifyHistory(ctx context.Context, tx *sql.Tx) (history, regis
 
 // Describe displays all of the registered migrations (with descriptions).
 func (m *Manager) Describe(_ context.Context) error {
-	m.Sequence.Describe(m.Log)
+	m.Sequence.Describe(m.printfReceiver())
 	return nil
 }
 
+// Heads displays the heads of the registered sequence of migrations. If the
+// sequence has diverged into multiple heads, `ErrMultipleHeads` is returned
+// (after displaying every head) since a merge migration is needed.
+func (m *Manager) Heads(_ context.Context) error {
+	heads := m.Sequence.Heads()
+	for _, head := range heads {
+		m.printf("%s: %s", head.Revision, head.ExtendedDescription())
+	}
+
+	return multipleHeadsError(heads)
+}
+
 // Version displays the revision of the most recent migration to be applied
 func (m *Manager) Version(ctx context.Context, opts ...ApplyOption) error {
 	migration, err := m.GetVersion(ctx, opts...)
@@ -560,23 +1587,31 @@ func (m *Manager) Version(ctx context.Context, opts ...ApplyOption) error {
 	}
 
 	if migration == nil {
-		m.Log.Printf("No migrations have been run")
+		m.printf("No migrations have been run")
 	} else {
-		m.Log.Printf(
+		m.printf(
 			"%s: %s (applied %s)",
-			migration.Revision, migration.Description, migration.createdAt,
+			migration.Revision, migration.Description, appliedSummary(migration.createdAt, migration.applyMetadata),
 		)
 	}
 	return nil
 }
 
 // IsApplied checks if a migration has already been applied.
+// If the migrations metadata table is outdated, `ErrMetadataTableOutdated` is
+// returned.
 //
 // NOTE: This assumes, but does not check, that the migrations metadata table
 // exists.
 func (m *Manager) IsApplied(ctx context.Context, tx *sql.Tx, migration Migration) (bool, error) {
+	err := m.requireLatestMetadataTableVersion(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
 	query := fmt.Sprintf(
-		"SELECT revision, previous, created_at FROM %s WHERE revision = %s",
+		"SELECT %s FROM %s WHERE revision = %s",
+		metadataColumns,
 		m.Provider.QuoteIdentifier(m.MetadataTable),
 		m.Provider.QueryParameter(1),
 	)
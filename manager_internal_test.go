@@ -0,0 +1,98 @@
+package golembic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBaselineStamped(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name               string
+		PastMigrationCount int
+		// Baselines marks which of the migrations are baselines.
+		Baselines []bool
+		Want      []bool
+	}{
+		{
+			Name:      "no baseline",
+			Baselines: []bool{false, false, false},
+			Want:      []bool{false, false, false},
+		},
+		{
+			Name:      "fresh database",
+			Baselines: []bool{false, false, true, false},
+			Want:      []bool{true, true, false, false},
+		},
+		{
+			Name:      "fresh database with multiple baselines",
+			Baselines: []bool{false, true, false, true, false},
+			Want:      []bool{true, true, true, false, false},
+		},
+		{
+			Name:      "fresh database with baseline at root",
+			Baselines: []bool{true, false},
+			Want:      []bool{false, false},
+		},
+		{
+			Name:               "existing history",
+			PastMigrationCount: 2,
+			Baselines:          []bool{false, true, false, true},
+			Want:               []bool{false, true, false, true},
+		},
+		{
+			Name:      "empty",
+			Baselines: []bool{},
+			Want:      []bool{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations := make([]Migration, len(tc.Baselines))
+			for i, baseline := range tc.Baselines {
+				migrations[i].Baseline = baseline
+			}
+
+			got := baselineStamped(tc.PastMigrationCount, migrations)
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("baselineStamped() = %v, want %v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestNextStep(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name               string
+		PastMigrationCount int
+		Baselines          []bool
+		Want               int
+	}{
+		{Name: "no baseline", Baselines: []bool{false, false, false}, Want: 1},
+		{Name: "fresh database", Baselines: []bool{false, false, true, false}, Want: 3},
+		{Name: "fresh database with multiple baselines", Baselines: []bool{false, true, false, true, false}, Want: 4},
+		{Name: "existing history", PastMigrationCount: 2, Baselines: []bool{false, true, false}, Want: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations := make([]Migration, len(tc.Baselines))
+			for i, baseline := range tc.Baselines {
+				migrations[i].Baseline = baseline
+			}
+
+			got := nextStep(tc.PastMigrationCount, migrations)
+			if len(got) != tc.Want {
+				t.Fatalf("len(nextStep()) = %d, want %d", len(got), tc.Want)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package golembic
 
 import (
 	"database/sql"
+	"log/slog"
+	"time"
 )
 
 // OptManagerMetadataTable sets the metadata table name on a manager.
@@ -49,6 +51,48 @@ func OptManagerLog(log PrintfReceiver) ManagerOption {
 	}
 }
 
+// OptManagerSlog sets a structured logger (backed by `handler`) on a
+// manager. All output is sent to `handler`, i.e. this takes precedence over
+// `OptManagerLog()` regardless of the order of the options.
+// If `handler` is `nil` the option will return an error.
+func OptManagerSlog(handler slog.Handler) ManagerOption {
+	return func(m *Manager) error {
+		if handler == nil {
+			return ErrNilInterface
+		}
+
+		m.Slog = slog.New(handler)
+		return nil
+	}
+}
+
+// OptManagerObserver adds an observer to a manager; observers are notified
+// in the order they are added. If `observer` is `nil` the option will return
+// an error.
+func OptManagerObserver(observer Observer) ManagerOption {
+	return func(m *Manager) error {
+		if observer == nil {
+			return ErrNilInterface
+		}
+
+		m.Observers = append(m.Observers, observer)
+		return nil
+	}
+}
+
+// OptManagerMetrics sets the metrics collector on a manager. If `metrics` is
+// `nil` the option will return an error.
+func OptManagerMetrics(metrics MetricsCollector) ManagerOption {
+	return func(m *Manager) error {
+		if metrics == nil {
+			return ErrNilInterface
+		}
+
+		m.Metrics = metrics
+		return nil
+	}
+}
+
 // OptDevelopmentMode sets the development mode flag on a manager.
 func OptDevelopmentMode(mode bool) ManagerOption {
 	return func(m *Manager) error {
@@ -56,3 +100,50 @@ func OptDevelopmentMode(mode bool) ManagerOption {
 		return nil
 	}
 }
+
+// OptManagerAutoUpgradeMetadataTable sets the flag on a manager indicating
+// that an outdated migrations metadata table should be upgraded automatically
+// (while holding the migration lock).
+func OptManagerAutoUpgradeMetadataTable(upgrade bool) ManagerOption {
+	return func(m *Manager) error {
+		m.AutoUpgradeMetadataTable = upgrade
+		return nil
+	}
+}
+
+// OptManagerAppliedBy sets the user stored in the `applied_by` column of the
+// migrations metadata table; by default this is the current user of the
+// process.
+func OptManagerAppliedBy(appliedBy string) ManagerOption {
+	return func(m *Manager) error {
+		m.AppliedBy = appliedBy
+		return nil
+	}
+}
+
+// OptManagerHostname sets the host stored in the `hostname` column of the
+// migrations metadata table; by default this is the hostname reported by the
+// operating system.
+func OptManagerHostname(hostname string) ManagerOption {
+	return func(m *Manager) error {
+		m.Hostname = hostname
+		return nil
+	}
+}
+
+// OptManagerApplicationVersion sets the application version stored in the
+// `app_version` column of the migrations metadata table.
+func OptManagerApplicationVersion(version string) ManagerOption {
+	return func(m *Manager) error {
+		m.ApplicationVersion = version
+		return nil
+	}
+}
+
+// OptManagerMigrationLockTimeout sets the migration lock timeout on a manager.
+func OptManagerMigrationLockTimeout(d time.Duration) ManagerOption {
+	return func(m *Manager) error {
+		m.MigrationLockTimeout = d
+		return nil
+	}
+}
@@ -0,0 +1,272 @@
+package golembic_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/dhermes/golembic"
+	"github.com/dhermes/golembic/sqlite3"
+)
+
+// testLog implements `golembic.PrintfReceiver` and sends log lines to the
+// test log.
+type testLog struct {
+	t *testing.T
+}
+
+func (tl testLog) Printf(format string, a ...interface{}) (n int, err error) {
+	tl.t.Helper()
+	tl.t.Logf(format, a...)
+	return 0, nil
+}
+
+// newSQLiteManager creates a manager for `migrations` backed by a SQLite
+// database in a temporary directory.
+func newSQLiteManager(t *testing.T, migrations *golembic.Migrations, opts ...golembic.ManagerOption) *golembic.Manager {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "golembic.db")
+	provider, err := sqlite3.New(
+		sqlite3.OptDataSourceName("file:"+filename),
+		sqlite3.OptDriverName("sqlite"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append(
+		[]golembic.ManagerOption{
+			golembic.OptManagerProvider(provider),
+			golembic.OptManagerSequence(migrations),
+			golembic.OptManagerLog(testLog{t: t}),
+		},
+		opts...,
+	)
+	m, err := golembic.NewManager(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		err := m.CloseConnectionPool()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	return m
+}
+
+// newSequence creates a linear sequence of migrations, each created from
+// the corresponding options (`OptPrevious()` is added for every migration
+// after the root).
+func newSequence(t *testing.T, opts ...[]golembic.MigrationOption) *golembic.Migrations {
+	t.Helper()
+
+	root, err := golembic.NewMigration(opts[0]...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := golembic.NewSequence(*root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := root.Revision
+	for _, migrationOpts := range opts[1:] {
+		migrationOpts = append([]golembic.MigrationOption{golembic.OptPrevious(previous)}, migrationOpts...)
+		migration, err := golembic.NewMigration(migrationOpts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = migrations.Register(*migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous = migration.Revision
+	}
+
+	return migrations
+}
+
+// appliedRows returns the `dirty` flag for each row in the migrations
+// metadata table, keyed by revision.
+func appliedRows(t *testing.T, m *golembic.Manager) map[string]bool {
+	t.Helper()
+
+	ctx := context.Background()
+	pool, err := m.EnsureConnectionPool(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := fmt.Sprintf("SELECT revision, dirty FROM %s", m.MetadataTable)
+	rows, err := pool.QueryContext(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var revision string
+		var dirty bool
+		err = rows.Scan(&revision, &dirty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		applied[revision] = dirty
+	}
+	err = rows.Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return applied
+}
+
+func TestApplyMigrationConnDirty(t *testing.T) {
+	t.Parallel()
+
+	errUpConn := errors.New("UpConn failed")
+	cases := []struct {
+		Name    string
+		Options []golembic.MigrationOption
+		Err     error
+		Dirty   bool
+		Applied bool
+	}{
+		{
+			Name: "applied",
+			Options: []golembic.MigrationOption{
+				golembic.OptUpConn(func(ctx context.Context, conn *sql.Conn) error {
+					_, err := conn.ExecContext(ctx, "CREATE TABLE t2 (id INTEGER)")
+					return err
+				}),
+			},
+			Applied: true,
+		},
+		{
+			Name: "UpConn fails",
+			Options: []golembic.MigrationOption{
+				golembic.OptUpConn(func(_ context.Context, _ *sql.Conn) error {
+					return errUpConn
+				}),
+			},
+			Err:     golembic.ErrMigrationDirty,
+			Dirty:   true,
+			Applied: true,
+		},
+		{
+			Name: "timeout setup fails",
+			Options: []golembic.MigrationOption{
+				golembic.OptStatementTimeout(time.Second),
+				golembic.OptUpConn(func(_ context.Context, _ *sql.Conn) error {
+					return errUpConn
+				}),
+			},
+			Err: golembic.ErrTimeoutUnsupported,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations := newSequence(
+				t,
+				[]golembic.MigrationOption{golembic.OptRevision("a"), golembic.OptUpFromSQL("CREATE TABLE t1 (id INTEGER)")},
+				append([]golembic.MigrationOption{golembic.OptRevision("b")}, tc.Options...),
+			)
+			m := newSQLiteManager(t, migrations)
+
+			err := m.Up(context.Background())
+			if tc.Err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("error = %v, want %v", err, tc.Err)
+			}
+			if tc.Err != nil && !tc.Dirty && errors.Is(err, golembic.ErrMigrationDirty) {
+				t.Fatalf("error = %v, want a migration that is not dirty", err)
+			}
+
+			applied := appliedRows(t, m)
+			dirty, ok := applied["b"]
+			if ok != tc.Applied || dirty != tc.Dirty {
+				t.Fatalf("row for b: present = %t, dirty = %t; want present = %t, dirty = %t", ok, dirty, tc.Applied, tc.Dirty)
+			}
+		})
+	}
+}
+
+func TestBaselineFreshDatabase(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name  string
+		Apply func(context.Context, *golembic.Manager) error
+		Want  []string
+	}{
+		{
+			Name: "Up",
+			Apply: func(ctx context.Context, m *golembic.Manager) error {
+				return m.Up(ctx)
+			},
+			Want: []string{"a", "b", "c", "d"},
+		},
+		{
+			Name: "UpOne",
+			Apply: func(ctx context.Context, m *golembic.Manager) error {
+				return m.UpOne(ctx)
+			},
+			Want: []string{"a", "b", "c"},
+		},
+		{
+			Name: "UpTo",
+			Apply: func(ctx context.Context, m *golembic.Manager) error {
+				return m.UpTo(ctx, golembic.OptApplyRevision("c"))
+			},
+			Want: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			// NOTE: The migrations replaced by the baseline fail if they are
+			//       run, so they must be stamped on a fresh database.
+			migrations := newSequence(
+				t,
+				[]golembic.MigrationOption{golembic.OptRevision("a"), golembic.OptUpFromSQL("SELECT * FROM replaced")},
+				[]golembic.MigrationOption{golembic.OptRevision("b"), golembic.OptUpFromSQL("SELECT * FROM replaced")},
+				[]golembic.MigrationOption{golembic.OptRevision("c"), golembic.OptBaseline(true), golembic.OptUpFromSQL("CREATE TABLE t (id INTEGER)")},
+				[]golembic.MigrationOption{golembic.OptRevision("d"), golembic.OptUpFromSQL("INSERT INTO t (id) VALUES (1)")},
+			)
+			m := newSQLiteManager(t, migrations)
+
+			err := tc.Apply(context.Background(), m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			applied := appliedRows(t, m)
+			if len(applied) != len(tc.Want) {
+				t.Fatalf("applied = %v, want %v", applied, tc.Want)
+			}
+			for _, revision := range tc.Want {
+				dirty, ok := applied[revision]
+				if !ok || dirty {
+					t.Fatalf("applied = %v, want %v", applied, tc.Want)
+				}
+			}
+		})
+	}
+}
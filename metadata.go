@@ -0,0 +1,80 @@
+package golembic
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// ApplyMetadata describes how a migration was applied: who applied it, from
+// which host, how long it took and which version of the application applied
+// it. These values are stored in the migrations metadata table and any of
+// them may be empty, e.g. for a migration that was applied before they were
+// recorded.
+type ApplyMetadata struct {
+	AppliedBy          string
+	Hostname           string
+	Duration           time.Duration
+	ApplicationVersion string
+}
+
+// describe produces a human readable summary of the metadata, e.g.
+// "by alice on web-1 in 12ms, version v1.2.3"; empty values are omitted.
+func (am ApplyMetadata) describe() string {
+	parts := []string{}
+	if am.AppliedBy != "" {
+		parts = append(parts, "by "+am.AppliedBy)
+	}
+	if am.Hostname != "" {
+		parts = append(parts, "on "+am.Hostname)
+	}
+	if am.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("in %s", am.Duration))
+	}
+	summary := strings.Join(parts, " ")
+
+	if am.ApplicationVersion == "" {
+		return summary
+	}
+	if summary == "" {
+		return "version " + am.ApplicationVersion
+	}
+	return summary + ", version " + am.ApplicationVersion
+}
+
+// defaultAppliedBy determines the current user from the environment, to be
+// stored as `applied_by` in the migrations metadata table.
+func defaultAppliedBy() string {
+	u, err := user.Current()
+	if err == nil && u.Username != "" {
+		return u.Username
+	}
+
+	if username := os.Getenv("USER"); username != "" {
+		return username
+	}
+	return os.Getenv("USERNAME")
+}
+
+// defaultHostname determines the current hostname from the environment, to be
+// stored as `hostname` in the migrations metadata table.
+func defaultHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
+
+// appliedSummary describes when (and how) a migration was applied, e.g.
+// "2024-06-14 02:30:35 +0000 UTC by alice on web-1 in 12ms".
+func appliedSummary(createdAt time.Time, am ApplyMetadata) string {
+	description := am.describe()
+	if description == "" {
+		return createdAt.String()
+	}
+
+	return fmt.Sprintf("%s %s", createdAt, description)
+}
@@ -0,0 +1,62 @@
+package golembic
+
+import (
+	"context"
+	"time"
+)
+
+// collectLockWait records the time spent waiting to acquire the migration
+// lock, if the manager has a metrics collector.
+func (m *Manager) collectLockWait(wait time.Duration) {
+	if m.Metrics == nil {
+		return
+	}
+
+	m.Metrics.ObserveLockWait(wait)
+}
+
+// collectPending records the number of registered migrations that are not
+// in `applied`, if the manager has a metrics collector.
+func (m *Manager) collectPending(applied []string) {
+	if m.Metrics == nil {
+		return
+	}
+
+	m.Metrics.SetPending(len(m.Sequence.Pending(applied)))
+}
+
+// collectReport records the outcome of every migration that was run in
+// `report`, if the manager has a metrics collector. Migrations that were
+// stamped, skipped or rolled back are not recorded. The number of pending
+// migrations is then refreshed from the migrations metadata table.
+func (m *Manager) collectReport(ctx context.Context, report *ApplyReport) {
+	if m.Metrics == nil {
+		return
+	}
+
+	for _, result := range report.Migrations {
+		switch result.Status {
+		case StatusApplied, StatusFailed, StatusDirty:
+			m.Metrics.ObserveMigration(result.Revision, result.Status, result.Duration)
+		}
+	}
+
+	m.refreshPending(ctx)
+}
+
+// refreshPending re-reads the applied revisions from the migrations metadata
+// table and records the number of pending migrations. This is best effort:
+// if the table can't be read, the previous value is left in place.
+func (m *Manager) refreshPending(ctx context.Context) {
+	history, err := m.storedHistory(ctx, false)
+	if err != nil {
+		return
+	}
+
+	applied, err := m.appliedRevisions(history)
+	if err != nil {
+		return
+	}
+
+	m.collectPending(applied)
+}
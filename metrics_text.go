@@ -0,0 +1,185 @@
+package golembic
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NOTE: Ensure that
+//   - `TextCollector` satisfies `MetricsCollector`.
+//   - `TextCollector` satisfies `io.WriterTo`.
+var (
+	_ MetricsCollector = (*TextCollector)(nil)
+	_ io.WriterTo      = (*TextCollector)(nil)
+)
+
+var (
+	// labelEscaper escapes a label value for the text exposition format.
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	// DefaultDurationBuckets are the (upper bounds, in seconds) of the
+	// histogram buckets used by `NewTextCollector()` if none are provided.
+	DefaultDurationBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300}
+)
+
+// histogram is a cumulative histogram in the style of the Prometheus text
+// exposition format.
+type histogram struct {
+	// counts[i] is the number of observations less than or equal to
+	// `buckets[i]`.
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, seconds float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+
+	for i, bucket := range buckets {
+		if seconds <= bucket {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// TextCollector implements `MetricsCollector` by keeping metrics in memory
+// and exposing them in the Prometheus text exposition format via `WriteTo()`;
+// see the `metricshttp` package for an HTTP handler. This does not depend on a
+// Prometheus client library. It is safe for concurrent use.
+type TextCollector struct {
+	mutex     sync.Mutex
+	buckets   []float64
+	applied   uint64
+	failed    uint64
+	durations map[string]*histogram
+	lockWait  histogram
+	pending   int
+}
+
+// NewTextCollector creates a new text exposition collector. The `buckets`
+// are the (upper bounds, in seconds) of the buckets for the histograms; if
+// none are provided, `DefaultDurationBuckets` is used.
+func NewTextCollector(buckets ...float64) *TextCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	return &TextCollector{
+		buckets:   sorted,
+		durations: map[string]*histogram{},
+	}
+}
+
+// ObserveMigration records the outcome of a migration that was run. A
+// migration that is `StatusDirty` is counted as failed.
+func (tc *TextCollector) ObserveMigration(revision string, status MigrationStatus, duration time.Duration) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if status == StatusApplied {
+		tc.applied++
+	} else {
+		tc.failed++
+	}
+
+	h, ok := tc.durations[revision]
+	if !ok {
+		h = &histogram{}
+		tc.durations[revision] = h
+	}
+	h.observe(tc.buckets, duration.Seconds())
+}
+
+// ObserveLockWait records the time spent waiting to acquire the migration
+// lock.
+func (tc *TextCollector) ObserveLockWait(duration time.Duration) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	tc.lockWait.observe(tc.buckets, duration.Seconds())
+}
+
+// SetPending records the number of registered migrations that have not yet
+// been applied.
+func (tc *TextCollector) SetPending(count int) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	tc.pending = count
+}
+
+// WriteTo writes the metrics to `w` in the Prometheus text exposition
+// format.
+func (tc *TextCollector) WriteTo(w io.Writer) (int64, error) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	var sb strings.Builder
+	writeHeader(&sb, "golembic_migrations_applied_total", "counter", "Number of migrations applied.")
+	fmt.Fprintf(&sb, "golembic_migrations_applied_total %d\n", tc.applied)
+	writeHeader(&sb, "golembic_migrations_failed_total", "counter", "Number of migrations that failed (or were left dirty).")
+	fmt.Fprintf(&sb, "golembic_migrations_failed_total %d\n", tc.failed)
+
+	name := "golembic_migration_duration_seconds"
+	writeHeader(&sb, name, "histogram", "Time taken to run a migration.")
+	revisions := make([]string, 0, len(tc.durations))
+	for revision := range tc.durations {
+		revisions = append(revisions, revision)
+	}
+	sort.Strings(revisions)
+	for _, revision := range revisions {
+		label := `revision="` + labelEscaper.Replace(revision) + `"`
+		writeHistogram(&sb, name, label, tc.buckets, tc.durations[revision])
+	}
+
+	name = "golembic_migration_lock_wait_seconds"
+	writeHeader(&sb, name, "histogram", "Time spent waiting to acquire the migration lock.")
+	writeHistogram(&sb, name, "", tc.buckets, &tc.lockWait)
+
+	writeHeader(&sb, "golembic_migrations_pending", "gauge", "Number of migrations that have not been applied.")
+	fmt.Fprintf(&sb, "golembic_migrations_pending %d\n", tc.pending)
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// writeHeader writes the `HELP` and `TYPE` lines for a metric.
+func writeHeader(sb *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(sb, "# TYPE %s %s\n", name, kind)
+}
+
+// writeHistogram writes the bucket, sum and count lines for a histogram. The
+// `label` (if not empty) is included along with the `le` label for buckets.
+func writeHistogram(sb *strings.Builder, name, label string, buckets []float64, h *histogram) {
+	prefix := ""
+	labels := ""
+	if label != "" {
+		prefix = label + ","
+		labels = "{" + label + "}"
+	}
+
+	for i, bucket := range buckets {
+		var count uint64
+		if h.counts != nil {
+			count = h.counts[i]
+		}
+		le := strconv.FormatFloat(bucket, 'g', -1, 64)
+		fmt.Fprintf(sb, "%s_bucket{%sle=%q} %d\n", name, prefix, le, count)
+	}
+	fmt.Fprintf(sb, "%s_bucket{%sle=\"+Inf\"} %d\n", name, prefix, h.count)
+	fmt.Fprintf(sb, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(sb, "%s_count%s %d\n", name, labels, h.count)
+}
@@ -0,0 +1,121 @@
+package golembic_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhermes/golembic"
+)
+
+func TestTextCollectorWriteTo(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name    string
+		Buckets []float64
+		Observe func(*golembic.TextCollector)
+		Want    string
+	}{
+		{
+			Name:    "empty",
+			Buckets: []float64{1},
+			Observe: func(_ *golembic.TextCollector) {},
+			Want: `# HELP golembic_migrations_applied_total Number of migrations applied.
+# TYPE golembic_migrations_applied_total counter
+golembic_migrations_applied_total 0
+# HELP golembic_migrations_failed_total Number of migrations that failed (or were left dirty).
+# TYPE golembic_migrations_failed_total counter
+golembic_migrations_failed_total 0
+# HELP golembic_migration_duration_seconds Time taken to run a migration.
+# TYPE golembic_migration_duration_seconds histogram
+# HELP golembic_migration_lock_wait_seconds Time spent waiting to acquire the migration lock.
+# TYPE golembic_migration_lock_wait_seconds histogram
+golembic_migration_lock_wait_seconds_bucket{le="1"} 0
+golembic_migration_lock_wait_seconds_bucket{le="+Inf"} 0
+golembic_migration_lock_wait_seconds_sum 0
+golembic_migration_lock_wait_seconds_count 0
+# HELP golembic_migrations_pending Number of migrations that have not been applied.
+# TYPE golembic_migrations_pending gauge
+golembic_migrations_pending 0
+`,
+		},
+		{
+			Name:    "observations",
+			Buckets: []float64{1, 0.5},
+			Observe: func(tc *golembic.TextCollector) {
+				tc.ObserveMigration("c", golembic.StatusDirty, 2*time.Second)
+				tc.ObserveMigration(`a"b`, golembic.StatusApplied, 750*time.Millisecond)
+				tc.ObserveMigration("c", golembic.StatusFailed, 250*time.Millisecond)
+				tc.ObserveLockWait(100 * time.Millisecond)
+				tc.SetPending(3)
+			},
+			Want: `# HELP golembic_migrations_applied_total Number of migrations applied.
+# TYPE golembic_migrations_applied_total counter
+golembic_migrations_applied_total 1
+# HELP golembic_migrations_failed_total Number of migrations that failed (or were left dirty).
+# TYPE golembic_migrations_failed_total counter
+golembic_migrations_failed_total 2
+# HELP golembic_migration_duration_seconds Time taken to run a migration.
+# TYPE golembic_migration_duration_seconds histogram
+golembic_migration_duration_seconds_bucket{revision="a\"b",le="0.5"} 0
+golembic_migration_duration_seconds_bucket{revision="a\"b",le="1"} 1
+golembic_migration_duration_seconds_bucket{revision="a\"b",le="+Inf"} 1
+golembic_migration_duration_seconds_sum{revision="a\"b"} 0.75
+golembic_migration_duration_seconds_count{revision="a\"b"} 1
+golembic_migration_duration_seconds_bucket{revision="c",le="0.5"} 1
+golembic_migration_duration_seconds_bucket{revision="c",le="1"} 1
+golembic_migration_duration_seconds_bucket{revision="c",le="+Inf"} 2
+golembic_migration_duration_seconds_sum{revision="c"} 2.25
+golembic_migration_duration_seconds_count{revision="c"} 2
+# HELP golembic_migration_lock_wait_seconds Time spent waiting to acquire the migration lock.
+# TYPE golembic_migration_lock_wait_seconds histogram
+golembic_migration_lock_wait_seconds_bucket{le="0.5"} 1
+golembic_migration_lock_wait_seconds_bucket{le="1"} 1
+golembic_migration_lock_wait_seconds_bucket{le="+Inf"} 1
+golembic_migration_lock_wait_seconds_sum 0.1
+golembic_migration_lock_wait_seconds_count 1
+# HELP golembic_migrations_pending Number of migrations that have not been applied.
+# TYPE golembic_migrations_pending gauge
+golembic_migrations_pending 3
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			collector := golembic.NewTextCollector(tc.Buckets...)
+			tc.Observe(collector)
+
+			var sb strings.Builder
+			n, err := collector.WriteTo(&sb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sb.String(); got != tc.Want {
+				t.Fatalf("WriteTo() =\n%s\nwant\n%s", got, tc.Want)
+			}
+			if n != int64(len(tc.Want)) {
+				t.Fatalf("WriteTo() wrote %d bytes, want %d", n, len(tc.Want))
+			}
+		})
+	}
+}
+
+func TestNewTextCollectorDefaultBuckets(t *testing.T) {
+	t.Parallel()
+
+	var sb strings.Builder
+	_, err := golembic.NewTextCollector().WriteTo(&sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Count(sb.String(), "golembic_migration_lock_wait_seconds_bucket{")
+	want := len(golembic.DefaultDurationBuckets) + 1
+	if got != want {
+		t.Fatalf("lock wait buckets = %d, want %d", got, want)
+	}
+}
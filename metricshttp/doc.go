@@ -0,0 +1,4 @@
+// Package metricshttp provides an HTTP handler that exposes the metrics
+// collected about golembic migration runs, so that the core package does not
+// depend on `net/http`.
+package metricshttp
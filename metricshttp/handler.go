@@ -0,0 +1,22 @@
+package metricshttp
+
+import (
+	"io"
+	"net/http"
+)
+
+const (
+	// ContentType is the content type for the Prometheus text exposition
+	// format.
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Handler returns an HTTP handler that writes the metrics from `collector`
+// (e.g. a `golembic.TextCollector`) in the Prometheus text exposition format
+// so that it can be used for a `/metrics` endpoint.
+func Handler(collector io.WriterTo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = collector.WriteTo(w)
+	})
+}
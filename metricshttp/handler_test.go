@@ -0,0 +1,54 @@
+package metricshttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhermes/golembic"
+	"github.com/dhermes/golembic/metricshttp"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	collector := golembic.NewTextCollector()
+	collector.ObserveMigration("a", golembic.StatusApplied, time.Second)
+	collector.SetPending(2)
+
+	cases := []struct {
+		Name   string
+		Method string
+	}{
+		{Name: "GET", Method: http.MethodGet},
+		{Name: "HEAD", Method: http.MethodHead},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(tc.Method, "/metrics", nil)
+			metricshttp.Handler(collector).ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+			}
+			if got := recorder.Header().Get("Content-Type"); got != metricshttp.ContentType {
+				t.Fatalf("Content-Type = %q, want %q", got, metricshttp.ContentType)
+			}
+
+			var sb strings.Builder
+			_, err := collector.WriteTo(&sb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := recorder.Body.String(); got != sb.String() {
+				t.Fatalf("body =\n%s\nwant\n%s", got, sb.String())
+			}
+		})
+	}
+}
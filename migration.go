@@ -4,11 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
 const (
 	milestoneSuffix = " [MILESTONE]"
+	baselineSuffix  = " [BASELINE]"
 )
 
 // Migration represents an individual migration to be applied; typically as
@@ -18,6 +20,11 @@ type Migration struct {
 	// preceding this one. If absent, this indicates that this migration is
 	// the "base" or "root" migration.
 	Previous string
+	// Merges is the revision identifiers for any **additional** parents of
+	// this migration, beyond `Previous`. This is used for a merge migration
+	// that joins two (or more) branches of the sequence, e.g. when two
+	// feature branches each add a migration with the same `Previous`.
+	Merges []string
 	// Revision is an opaque name that uniquely identifies a migration. It
 	// is required for a migration to be valid.
 	Revision string
@@ -35,6 +42,36 @@ type Migration struct {
 	// milestone marks the last point where old / new versions of application
 	// code should be expected to be able to interact with the current schema.
 	Milestone bool
+	// Baseline is a flag indicating if the current migration is a baseline. A
+	// baseline replaces every migration before it (from the root up to and
+	// including `Previous`) and applying it must produce the same schema as
+	// applying all of the migrations it replaces. This is intended to be used
+	// to "squash" a long history: when migrations are applied to a fresh
+	// database, only the (last) baseline is run and the migrations it replaces
+	// are stamped as applied. For a database that already has history, the
+	// baseline itself is stamped rather than run. A baseline cannot be rolled
+	// back.
+	Baseline bool
+	// Checksum is an optional checksum of the contents of the migration. It
+	// will be stored in the migrations metadata table when the migration is
+	// applied and is used to detect a migration that has been modified after
+	// being applied. It is computed automatically for migrations created from
+	// SQL statements (e.g. via `OptUpFromSQL()`) and can be set explicitly
+	// (e.g. via `OptChecksum()`) for other migrations.
+	Checksum string
+	// LockTimeout is an optional override for the lock timeout while the
+	// migration runs, e.g. so that a schema change can fail fast. If zero, the
+	// lock timeout configured for the connection (if any) is used. This
+	// requires a provider that satisfies `TimeoutProvider`.
+	LockTimeout time.Duration
+	// StatementTimeout is an optional override for the statement timeout
+	// while the migration runs, e.g. so that a long running backfill is not
+	// cancelled. If zero, the statement timeout configured for the connection
+	// (if any) is used. This requires a provider that satisfies
+	// `TimeoutProvider` and supports a statement timeout, i.e. PostgreSQL;
+	// MySQL (where `max_execution_time` only applies to `SELECT`) and SQLite
+	// return `ErrTimeoutUnsupported`.
+	StatementTimeout time.Duration
 	// Up is the function to be executed when a migration is being applied. Either
 	// this field or `UpConn` are required (not both) and this field should be
 	// the default choice in most cases. This function will be run in a transaction
@@ -45,17 +82,36 @@ type Migration struct {
 	// rare situations where a migration cannot run inside a transaction, e.g.
 	// a `CREATE UNIQUE INDEX CONCURRENTLY` statement.
 	UpConn UpMigrationConn
+	// Down is the function to be executed when a migration is being rolled
+	// back. This field is optional, but if it is not set (and neither is
+	// `DownConn`) then the migration cannot be rolled back. This function will
+	// be run in a transaction that also deletes the row for this migration from
+	// the migrations metadata table.
+	Down DownMigration
+	// DownConn is the non-transactional form of `Down`. This should be used in
+	// rare situations where a rollback cannot run inside a transaction, e.g.
+	// a `DROP INDEX CONCURRENTLY` statement.
+	DownConn DownMigrationConn
 	// createdAt is stored in the migrations metadata table and represents the
 	// moment when the migration was inserted into the table.  It is **not**
 	// exported because it is internal to the implementation and should not be
 	// specified by calling code.
 	createdAt time.Time
-	// serialID is an integer used for sorting migrations and will be stored
-	// in the migrations table. It is intended to be used for migrations
-	// retrieved via a SQL query to the migrations metadata table. It is
-	// **not** exported because it is internal to the implementation and should
-	// not be specified by calling code.
-	serialID uint32
+	// applyMetadata is stored in the migrations metadata table and describes
+	// how the migration was applied. It is **not** exported because it is
+	// internal to the implementation and should not be specified by calling
+	// code.
+	applyMetadata ApplyMetadata
+	// dirty is stored in the migrations metadata table and indicates that a
+	// non-transactional migration was started but has not been marked as
+	// complete. It is **not** exported because it is internal to the
+	// implementation and should not be specified by calling code.
+	dirty bool
+	// checksumSet indicates that `Checksum` was set explicitly (e.g. via
+	// `OptChecksum()`) rather than computed from a SQL statement, so that
+	// it is not replaced by a computed checksum. It is **not** exported
+	// because it is internal to the implementation.
+	checksumSet bool
 }
 
 // NewMigration creates a new migration from a variadic slice of options.
@@ -72,18 +128,65 @@ func NewMigration(opts ...MigrationOption) (*Migration, error) {
 }
 
 // ExtendedDescription is an extended form of `m.Description` that also
-// incorporates other information like whether `m` is a milestone.
+// incorporates other information like whether `m` is a baseline or a milestone.
 func (m Migration) ExtendedDescription() string {
+	description := m.Description
+	if m.Baseline {
+		description += baselineSuffix
+	}
 	if m.Milestone {
-		return m.Description + milestoneSuffix
+		description += milestoneSuffix
 	}
 
-	return m.Description
+	return description
+}
+
+// Transactional indicates if the migration runs inside a transaction, i.e.
+// if it uses `Up` rather than `UpConn`.
+func (m Migration) Transactional() bool {
+	return m.UpConn == nil
 }
 
-// Like is "almost" an equality check, it compares the `Previous` and `Revision`.
+// CreatedAt returns the moment when the migration was inserted into the
+// migrations metadata table. This will be the zero value for a migration that
+// was not retrieved from the migrations metadata table.
+func (m Migration) CreatedAt() time.Time {
+	return m.createdAt
+}
+
+// ApplyMetadata returns the metadata stored in the migrations metadata table
+// describing how the migration was applied. This will be the zero value for a
+// migration that was not retrieved from the migrations metadata table.
+func (m Migration) ApplyMetadata() ApplyMetadata {
+	return m.applyMetadata
+}
+
+// Dirty indicates that the migration is marked as "in progress" in the
+// migrations metadata table, i.e. a non-transactional migration was started
+// but did not complete and may have been partially applied. This will be
+// `false` for a migration that was not retrieved from the migrations metadata
+// table.
+func (m Migration) Dirty() bool {
+	return m.dirty
+}
+
+// Like is "almost" an equality check, it compares the `Previous`, `Merges`
+// and `Revision`.
 func (m Migration) Like(other Migration) bool {
-	return m.Previous == other.Previous && m.Revision == other.Revision
+	if m.Previous != other.Previous || m.Revision != other.Revision {
+		return false
+	}
+
+	if len(m.Merges) != len(other.Merges) {
+		return false
+	}
+	for i, merge := range m.Merges {
+		if merge != other.Merges[i] {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Compact gives a "limited" representation of the migration
@@ -92,15 +195,31 @@ func (m Migration) Compact() string {
 		return fmt.Sprintf("%s:NULL", m.Revision)
 	}
 
+	if len(m.Merges) > 0 {
+		return fmt.Sprintf("%s:%s+%s", m.Revision, m.Previous, strings.Join(m.Merges, "+"))
+	}
+
 	return fmt.Sprintf("%s:%s", m.Revision, m.Previous)
 }
 
+// Parents returns the revisions of all of the parents of this migration,
+// i.e. `Previous` followed by `Merges`. This will be empty for the root
+// migration.
+func (m Migration) Parents() []string {
+	if m.Previous == "" {
+		return nil
+	}
+
+	return append([]string{m.Previous}, m.Merges...)
+}
+
 // InvokeUp dispatches to `Up` or `UpConn`, depending on which is set. If both
 // or neither is set, that is considered an error. If `UpConn` needs to be invoked,
-// this lazily creates a new connection from a pool. It's crucial that the pool
-// sets the relevant timeouts when creating a new connection to make sure
-// migrations don't cause disruptions in application performance due to
-// accidentally holding locks for an extended period.
+// this lazily creates a new connection from a pool (and returns it to the pool
+// once `UpConn` exits). It's crucial that the pool sets the relevant timeouts
+// when creating a new connection to make sure migrations don't cause
+// disruptions in application performance due to accidentally holding locks for
+// an extended period.
 func (m Migration) InvokeUp(ctx context.Context, pool *sql.DB, tx *sql.Tx) error {
 	// Handle the `UpConn` case first.
 	if m.UpConn != nil {
@@ -108,12 +227,7 @@ func (m Migration) InvokeUp(ctx context.Context, pool *sql.DB, tx *sql.Tx) error
 			return fmt.Errorf("%w; both Up and UpConn are set", ErrCannotInvokeUp)
 		}
 
-		conn, err := pool.Conn(ctx)
-		if err != nil {
-			return err
-		}
-
-		return m.UpConn(ctx, conn)
+		return withConn(ctx, pool, m.UpConn)
 	}
 
 	// If neither `UpConn` nor `Up` is set, we can't invoke anything.
@@ -123,3 +237,54 @@ func (m Migration) InvokeUp(ctx context.Context, pool *sql.DB, tx *sql.Tx) error
 
 	return m.Up(ctx, tx)
 }
+
+// InvokeDown dispatches to `Down` or `DownConn`, depending on which is set. If
+// both or neither is set, that is considered an error. If `DownConn` needs to
+// be invoked, this lazily creates a new connection from a pool (the same
+// caveats about timeouts as in `InvokeUp()` apply).
+func (m Migration) InvokeDown(ctx context.Context, pool *sql.DB, tx *sql.Tx) error {
+	err := m.checkDown()
+	if err != nil {
+		return err
+	}
+
+	if m.DownConn != nil {
+		return withConn(ctx, pool, m.DownConn)
+	}
+
+	return m.Down(ctx, tx)
+}
+
+// withConn creates a new connection from a pool, invokes `fn` with it and
+// then closes the connection (i.e. returns it to the pool).
+func withConn(ctx context.Context, pool *sql.DB, fn func(context.Context, *sql.Conn) error) (err error) {
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := conn.Close()
+		err = maybeWrap(err, closeErr, "failed to close connection")
+	}()
+
+	err = fn(ctx, conn)
+	return
+}
+
+// checkDown verifies that exactly one of `Down` or `DownConn` is set, i.e.
+// that the migration can be rolled back.
+func (m Migration) checkDown() error {
+	if m.Baseline {
+		return fmt.Errorf("%w; revision: %q, a baseline cannot be rolled back", ErrCannotInvokeDown, m.Revision)
+	}
+
+	if m.Down != nil && m.DownConn != nil {
+		return fmt.Errorf("%w; revision: %q, both Down and DownConn are set", ErrCannotInvokeDown, m.Revision)
+	}
+
+	if m.Down == nil && m.DownConn == nil {
+		return fmt.Errorf("%w; revision: %q, neither Down nor DownConn are set", ErrCannotInvokeDown, m.Revision)
+	}
+
+	return nil
+}
@@ -3,7 +3,9 @@ package golembic
 import (
 	"context"
 	"database/sql"
+	"io/fs"
 	"io/ioutil"
+	"time"
 )
 
 // OptPrevious sets the previous on a migration.
@@ -42,6 +44,54 @@ func OptMilestone(milestone bool) MigrationOption {
 	}
 }
 
+// OptMerges sets the additional parents on a (merge) migration.
+func OptMerges(revisions ...string) MigrationOption {
+	return func(m *Migration) error {
+		m.Merges = revisions
+		return nil
+	}
+}
+
+// OptBaseline sets the baseline flag on a migration.
+func OptBaseline(baseline bool) MigrationOption {
+	return func(m *Migration) error {
+		m.Baseline = baseline
+		return nil
+	}
+}
+
+// OptLockTimeout sets the lock timeout used while the migration runs; this
+// overrides the lock timeout (if any) configured for the connection.
+func OptLockTimeout(timeout time.Duration) MigrationOption {
+	return func(m *Migration) error {
+		m.LockTimeout = timeout
+		return nil
+	}
+}
+
+// OptStatementTimeout sets the statement timeout used while the migration
+// runs; this overrides the statement timeout (if any) configured for the
+// connection. Only PostgreSQL has a statement timeout for every kind of
+// statement; with MySQL or SQLite, applying a migration with a statement
+// timeout fails with `ErrTimeoutUnsupported`.
+func OptStatementTimeout(timeout time.Duration) MigrationOption {
+	return func(m *Migration) error {
+		m.StatementTimeout = timeout
+		return nil
+	}
+}
+
+// OptChecksum sets the checksum on a migration. This takes precedence over
+// the checksum computed by `OptUpFromSQL()` (or `OptUpConnFromSQL()`),
+// regardless of the order of the options.
+func OptChecksum(checksum string) MigrationOption {
+	return func(m *Migration) error {
+		m.Checksum = checksum
+		m.checksumSet = true
+		return nil
+	}
+}
+
 // OptUp sets the `up` function on a migration.
 func OptUp(up UpMigration) MigrationOption {
 	return func(m *Migration) error {
@@ -55,15 +105,22 @@ func OptUp(up UpMigration) MigrationOption {
 }
 
 // OptUpFromSQL returns an option that sets the `up` function to execute a
-// SQL statement.
+// SQL statement. This also sets the checksum of the migration based on the
+// SQL statement (replacing the checksum of any earlier statement), unless a
+// checksum has been set explicitly via `OptChecksum()`.
 func OptUpFromSQL(statement string) MigrationOption {
 	up := func(ctx context.Context, tx *sql.Tx) error {
 		_, err := tx.ExecContext(ctx, statement)
 		return err
 	}
 
+	checksum := SQLChecksum(statement)
+
 	return func(m *Migration) error {
 		m.Up = up
+		if !m.checksumSet {
+			m.Checksum = checksum
+		}
 		return nil
 	}
 }
@@ -79,6 +136,17 @@ func OptUpFromFile(filename string) MigrationOption {
 	return OptUpFromSQL(string(statement))
 }
 
+// OptUpFromFS returns an option that sets the `up` function to execute a
+// SQL statement that is stored in a file in `fsys`, e.g. an `embed.FS`.
+func OptUpFromFS(fsys fs.FS, name string) MigrationOption {
+	statement, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return OptAlwaysError(err)
+	}
+
+	return OptUpFromSQL(string(statement))
+}
+
 // OptUpConn sets the non-transactional `up` function on a migration.
 func OptUpConn(up UpMigrationConn) MigrationOption {
 	return func(m *Migration) error {
@@ -92,15 +160,22 @@ func OptUpConn(up UpMigrationConn) MigrationOption {
 }
 
 // OptUpConnFromSQL returns an option that sets the non-transctional `up`
-// function to execute a SQL statement.
+// function to execute a SQL statement. This also sets the checksum of the
+// migration based on the SQL statement (replacing the checksum of any earlier
+// statement), unless a checksum has been set explicitly via `OptChecksum()`.
 func OptUpConnFromSQL(statement string) MigrationOption {
 	up := func(ctx context.Context, conn *sql.Conn) error {
 		_, err := conn.ExecContext(ctx, statement)
 		return err
 	}
 
+	checksum := SQLChecksum(statement)
+
 	return func(m *Migration) error {
 		m.UpConn = up
+		if !m.checksumSet {
+			m.Checksum = checksum
+		}
 		return nil
 	}
 }
@@ -116,6 +191,115 @@ func OptUpConnFromFile(filename string) MigrationOption {
 	return OptUpConnFromSQL(string(statement))
 }
 
+// OptUpConnFromFS returns an option that sets the non-transctional `up`
+// function to execute a SQL statement that is stored in a file in `fsys`,
+// e.g. an `embed.FS`.
+func OptUpConnFromFS(fsys fs.FS, name string) MigrationOption {
+	statement, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return OptAlwaysError(err)
+	}
+
+	return OptUpConnFromSQL(string(statement))
+}
+
+// OptDown sets the `down` function on a migration.
+func OptDown(down DownMigration) MigrationOption {
+	return func(m *Migration) error {
+		if down == nil {
+			return ErrNilInterface
+		}
+
+		m.Down = down
+		return nil
+	}
+}
+
+// OptDownFromSQL returns an option that sets the `down` function to execute a
+// SQL statement.
+func OptDownFromSQL(statement string) MigrationOption {
+	down := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, statement)
+		return err
+	}
+
+	return func(m *Migration) error {
+		m.Down = down
+		return nil
+	}
+}
+
+// OptDownFromFile returns an option that sets the `down` function to execute a
+// SQL statement that is stored in a file.
+func OptDownFromFile(filename string) MigrationOption {
+	statement, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return OptAlwaysError(err)
+	}
+
+	return OptDownFromSQL(string(statement))
+}
+
+// OptDownFromFS returns an option that sets the `down` function to execute a
+// SQL statement that is stored in a file in `fsys`, e.g. an `embed.FS`.
+func OptDownFromFS(fsys fs.FS, name string) MigrationOption {
+	statement, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return OptAlwaysError(err)
+	}
+
+	return OptDownFromSQL(string(statement))
+}
+
+// OptDownConn sets the non-transactional `down` function on a migration.
+func OptDownConn(down DownMigrationConn) MigrationOption {
+	return func(m *Migration) error {
+		if down == nil {
+			return ErrNilInterface
+		}
+
+		m.DownConn = down
+		return nil
+	}
+}
+
+// OptDownConnFromSQL returns an option that sets the non-transctional `down`
+// function to execute a SQL statement.
+func OptDownConnFromSQL(statement string) MigrationOption {
+	down := func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, statement)
+		return err
+	}
+
+	return func(m *Migration) error {
+		m.DownConn = down
+		return nil
+	}
+}
+
+// OptDownConnFromFile returns an option that sets the non-transctional `down`
+// function to execute a SQL statement that is stored in a file.
+func OptDownConnFromFile(filename string) MigrationOption {
+	statement, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return OptAlwaysError(err)
+	}
+
+	return OptDownConnFromSQL(string(statement))
+}
+
+// OptDownConnFromFS returns an option that sets the non-transctional `down`
+// function to execute a SQL statement that is stored in a file in `fsys`,
+// e.g. an `embed.FS`.
+func OptDownConnFromFS(fsys fs.FS, name string) MigrationOption {
+	statement, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return OptAlwaysError(err)
+	}
+
+	return OptDownConnFromSQL(string(statement))
+}
+
 // OptAlwaysError returns an option that always returns an error.
 func OptAlwaysError(err error) MigrationOption {
 	return func(m *Migration) error {
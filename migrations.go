@@ -1,28 +1,27 @@
 package golembic
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 )
 
-// NOTE: Ensure that
-//   - `Migrations.Since` satisfies `migrationsFilter`.
-//   - `Migrations.Until` satisfies `migrationsFilter`.
-var (
-	_ migrationsFilter = (*Migrations)(nil).Since
-	_ migrationsFilter = (*Migrations)(nil).Until
-)
-
 // Migrations represents a sequence of migrations to be applied.
+//
+// The migrations are stored in an ordered slice (in the order they were
+// registered) along with an index from revision to position in the slice, so
+// that `All()`, `Pending()`, `Since()`, `Until()` and `Between()` are linear
+// and `Get()` is constant time.
 type Migrations struct {
-	sequence map[string]Migration
-	lock     sync.Mutex
+	ordered []Migration
+	index   map[string]int
+	lock    sync.Mutex
 }
 
 // NewSequence creates a new sequence of migrations rooted in a single
 // base / root migration.
 func NewSequence(root Migration) (*Migrations, error) {
-	if root.Previous != "" {
+	if root.Previous != "" || len(root.Merges) > 0 {
 		err := fmt.Errorf(
 			"%w; previous: %q, revision: %q",
 			ErrNotRoot, root.Previous, root.Revision,
@@ -35,17 +34,19 @@ func NewSequence(root Migration) (*Migrations, error) {
 	}
 
 	m := &Migrations{
-		sequence: map[string]Migration{
-			root.Revision: root,
-		},
-		lock: sync.Mutex{},
+		ordered: []Migration{root},
+		index:   map[string]int{root.Revision: 0},
+		lock:    sync.Mutex{},
 	}
 	return m, nil
 }
 
 // Register adds a new migration to an existing sequence of migrations, if
 // possible. The new migration must have a previous migration and have a valid
-// revision that is not already registered.
+// revision that is not already registered. Every parent of the new migration
+// (i.e. `Previous` and `Merges`) must already be registered; multiple
+// migrations may share the same parent, which creates a branch that can
+// later be joined by a merge migration.
 func (m *Migrations) Register(migration Migration) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -54,24 +55,35 @@ func (m *Migrations) Register(migration Migration) error {
 		return fmt.Errorf("%w; revision: %q", ErrNoPrevious, migration.Revision)
 	}
 
-	if _, ok := m.sequence[migration.Previous]; !ok {
-		return fmt.Errorf(
-			"%w; revision: %q, previous: %q",
-			ErrPreviousNotRegistered, migration.Revision, migration.Previous,
-		)
+	seen := map[string]bool{}
+	for _, parent := range migration.Parents() {
+		if seen[parent] {
+			return fmt.Errorf(
+				"%w; revision: %q, parent: %q",
+				ErrDuplicateParent, migration.Revision, parent,
+			)
+		}
+		seen[parent] = true
+
+		if _, ok := m.index[parent]; !ok {
+			return fmt.Errorf(
+				"%w; revision: %q, previous: %q",
+				ErrPreviousNotRegistered, migration.Revision, parent,
+			)
+		}
 	}
 
 	if migration.Revision == "" {
 		return fmt.Errorf("%w; previous: %q", ErrMissingRevision, migration.Previous)
 	}
 
-	if _, ok := m.sequence[migration.Revision]; ok {
+	if _, ok := m.index[migration.Revision]; ok {
 		return fmt.Errorf("%w; revision: %q", ErrAlreadyRegistered, migration.Revision)
 	}
 
-	// NOTE: This crucially relies on `m.sequence` being locked.
-	migration.serialID = uint32(len(m.sequence))
-	m.sequence[migration.Revision] = migration
+	// NOTE: This crucially relies on `m.ordered` and `m.index` being locked.
+	m.index[migration.Revision] = len(m.ordered)
+	m.ordered = append(m.ordered, migration)
 	return nil
 }
 
@@ -108,86 +120,259 @@ func (m *Migrations) RegisterManyOpt(manyOpts ...[]MigrationOption) error {
 	return nil
 }
 
-// Root does a linear scan of every migration in the sequence and returns
-// the root migration. In the "general" case such a scan would be expensive, but
-// the number of migrations should always be a small number.
-//
-// NOTE: This does not verify or enforce the invariant that there must be
-// exactly one migration without a previous migration. This invariant is enforced
-// by the exported methods such as `Register()` and `RegisterMany()` and the
-// constructor `NewSequence()`.
-func (m *Migrations) Root() Migration {
+// Root returns the root migration, i.e. the first migration registered. If
+// the sequence is empty or the first migration has a previous migration, this
+// returns an error.
+func (m *Migrations) Root() (Migration, error) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	for _, migration := range m.sequence {
-		if migration.Previous == "" {
-			return migration
-		}
+	if len(m.ordered) == 0 {
+		return Migration{}, ErrNoRoot
+	}
+
+	root := m.ordered[0]
+	if root.Previous != "" {
+		err := fmt.Errorf(
+			"%w; previous: %q, revision: %q",
+			ErrNotRoot, root.Previous, root.Revision,
+		)
+		return Migration{}, err
 	}
 
-	return Migration{}
+	return root, nil
 }
 
-// All produces the migrations in the sequence, in order.
-//
-// NOTE: This does not verify or enforce the invariant that there must be
-// exactly one migration without a previous migration. This invariant is
-// enforced by the exported methods such as `Register()` and
-// `RegisterMany()` and the constructor `NewSequence()`.
+// All produces the migrations in the sequence, in order. Since every parent
+// of a migration must be registered before it, the order of registration is
+// a (deterministic) topological order of the migrations; this is the order
+// used even if the sequence has branches.
 func (m *Migrations) All() []Migration {
-	root := m.Root()
-
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	result := []Migration{root}
-	// Find the unique revision (without validation) that points at the
-	// current `previous`.
-	previous := root.Revision
-	for i := 0; i < len(m.sequence)-1; i++ {
-		for _, migration := range m.sequence {
-			if migration.Previous != previous {
+
+	result := make([]Migration, len(m.ordered))
+	copy(result, m.ordered)
+	return result
+}
+
+// Validate checks the integrity of the sequence and reports every problem
+// found: orphans (a parent that is not registered), cycles, multiple (or no)
+// roots, revisions that are not reachable from the root and parents that are
+// registered **after** a child. The exported methods such as `Register()`
+// and the constructor `NewSequence()` maintain these invariants, so this is
+// intended as a safety check before applying migrations.
+func (m *Migrations) Validate() error {
+	all := m.All()
+	if len(all) == 0 {
+		return ErrNoRoot
+	}
+
+	index := map[string]int{}
+	for i, migration := range all {
+		index[migration.Revision] = i
+	}
+
+	problems := []error{}
+	roots := []string{}
+	children := map[string][]string{}
+	for i, migration := range all {
+		if migration.Previous == "" {
+			roots = append(roots, migration.Revision)
+		}
+
+		for _, parent := range migration.Parents() {
+			position, ok := index[parent]
+			if !ok {
+				err := fmt.Errorf(
+					"%w; revision: %q, parent: %q",
+					ErrOrphanedMigration, migration.Revision, parent,
+				)
+				problems = append(problems, err)
 				continue
 			}
+			if position >= i {
+				err := fmt.Errorf(
+					"%w; revision: %q, parent: %q",
+					ErrSequenceOutOfOrder, migration.Revision, parent,
+				)
+				problems = append(problems, err)
+			}
+			children[parent] = append(children[parent], migration.Revision)
+		}
+	}
 
-			result = append(result, migration)
-			previous = migration.Revision
-			break
+	if len(roots) != 1 {
+		err := fmt.Errorf("%w; roots: %q", ErrMultipleRoots, roots)
+		problems = append(problems, err)
+	}
+
+	problems = append(problems, findCycles(all, index)...)
+
+	// Walk forward from the root(s) to find revisions that can't be reached.
+	reachable := map[string]bool{}
+	queue := append([]string{}, roots...)
+	for len(queue) > 0 {
+		revision := queue[0]
+		queue = queue[1:]
+		if reachable[revision] {
+			continue
+		}
+		reachable[revision] = true
+		queue = append(queue, children[revision]...)
+	}
+	for _, migration := range all {
+		if !reachable[migration.Revision] {
+			err := fmt.Errorf("%w; revision: %q", ErrUnreachableMigration, migration.Revision)
+			problems = append(problems, err)
 		}
 	}
 
-	return result
+	return errors.Join(problems...)
 }
 
-// Since returns the migrations that occur **after** `revision`.
-//
-// This utilizes `All()` and returns all migrations after the one that
-// matches `revision`. If none match, an error will be returned. If
-// `revision` is the **last** migration, the migrations returned will be an
-// empty slice.
-func (m *Migrations) Since(revision string) (int, []Migration, error) {
+// findCycles does a depth first search along the parents of every migration
+// and reports each cycle found. The `index` maps each revision to its
+// position in `all`.
+func findCycles(all []Migration, index map[string]int) []error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	problems := []error{}
+	state := make([]int, len(all))
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		for _, parent := range all[i].Parents() {
+			j, ok := index[parent]
+			if !ok {
+				continue
+			}
+
+			switch state[j] {
+			case visiting:
+				err := fmt.Errorf(
+					"%w; revision: %q, parent: %q",
+					ErrSequenceCycle, all[i].Revision, parent,
+				)
+				problems = append(problems, err)
+			case unvisited:
+				visit(j)
+			}
+		}
+		state[i] = visited
+	}
+
+	for i := range all {
+		if state[i] == unvisited {
+			visit(i)
+		}
+	}
+
+	return problems
+}
+
+// Heads returns the migrations (in order) that are not a parent of any other
+// migration. A sequence that has not diverged has exactly one head; if there
+// are multiple heads, a merge migration is needed to join them.
+func (m *Migrations) Heads() []Migration {
 	all := m.All()
-	found := false
+	parents := map[string]bool{}
+	for _, migration := range all {
+		for _, parent := range migration.Parents() {
+			parents[parent] = true
+		}
+	}
 
-	result := []Migration{}
-	pastMigrationCount := 0
+	heads := []Migration{}
 	for _, migration := range all {
-		if found {
+		if !parents[migration.Revision] {
+			heads = append(heads, migration)
+		}
+	}
+
+	return heads
+}
+
+// Pending returns the migrations (in order) whose revision is not in
+// `applied`. Since every parent of a migration is registered before it, each
+// pending migration comes after its parents, even if the branches of the
+// sequence were applied in a different order than they were registered.
+func (m *Migrations) Pending(applied []string) []Migration {
+	isApplied := map[string]bool{}
+	for _, revision := range applied {
+		isApplied[revision] = true
+	}
+
+	result := []Migration{}
+	for _, migration := range m.All() {
+		if !isApplied[migration.Revision] {
 			result = append(result, migration)
+		}
+	}
+
+	return result
+}
+
+// PendingUntil returns the pending migrations (in order) that are needed to
+// apply `revision`, i.e. `revision` and its ancestors whose revision is not in
+// `applied`. If `revision` is not registered, an error will be returned. If
+// `revision` has already been applied, the migrations returned will be an
+// empty slice.
+func (m *Migrations) PendingUntil(applied []string, revision string) ([]Migration, error) {
+	if m.Get(revision) == nil {
+		err := fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, revision)
+		return nil, err
+	}
+
+	// Walk backward from `revision` to find all of its ancestors.
+	needed := map[string]bool{}
+	queue := []string{revision}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if needed[current] {
 			continue
 		}
+		needed[current] = true
 
-		pastMigrationCount++
-		if migration.Revision == revision {
-			found = true
+		migration := m.Get(current)
+		if migration != nil {
+			queue = append(queue, migration.Parents()...)
+		}
+	}
+
+	result := []Migration{}
+	for _, migration := range m.Pending(applied) {
+		if needed[migration.Revision] {
+			result = append(result, migration)
 		}
 	}
 
-	if !found {
+	return result, nil
+}
+
+// Since returns the migrations that occur **after** `revision`.
+//
+// This utilizes `All()` and returns all migrations after the one that
+// matches `revision`. If none match, an error will be returned. If
+// `revision` is the **last** migration, the migrations returned will be an
+// empty slice. If the sequence has branches, this is based on the order the
+// migrations were registered; use `Pending()` to determine the migrations
+// that have not been applied.
+func (m *Migrations) Since(revision string) (int, []Migration, error) {
+	all := m.All()
+	position, ok := m.position(revision)
+	if !ok {
 		err := fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, revision)
 		return 0, nil, err
 	}
 
+	pastMigrationCount := position + 1
+	result := append([]Migration{}, all[pastMigrationCount:]...)
 	return pastMigrationCount, result, nil
 }
 
@@ -197,18 +382,8 @@ func (m *Migrations) Since(revision string) (int, []Migration, error) {
 // that matches `revision`. If none match, an error will be returned.
 func (m *Migrations) Until(revision string) (int, []Migration, error) {
 	all := m.All()
-	found := false
-
-	result := []Migration{}
-	for _, migration := range all {
-		result = append(result, migration)
-		if migration.Revision == revision {
-			found = true
-			break
-		}
-	}
-
-	if !found {
+	position, ok := m.position(revision)
+	if !ok {
 		err := fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, revision)
 		return 0, nil, err
 	}
@@ -216,7 +391,7 @@ func (m *Migrations) Until(revision string) (int, []Migration, error) {
 	// I.e. we are not filtering any migrations from the beginning of the
 	// sequence.
 	pastMigrationCount := 0
-	return pastMigrationCount, result, nil
+	return pastMigrationCount, all[:position+1], nil
 }
 
 // Between returns the migrations that occur between two revisions.
@@ -224,39 +399,23 @@ func (m *Migrations) Until(revision string) (int, []Migration, error) {
 // This can be seen as a combination of `Since()` and `Until()`.
 func (m *Migrations) Between(since, until string) (int, []Migration, error) {
 	all := m.All()
-	foundSince := false
-	foundUntil := false
-
-	result := []Migration{}
-	pastMigrationCount := 0
-	for _, migration := range all {
-		if foundSince {
-			if foundUntil {
-				break
-			}
-			result = append(result, migration)
-		}
-
-		pastMigrationCount++
-		if migration.Revision == since {
-			foundSince = true
-		}
-
-		if migration.Revision == until {
-			foundUntil = true
-		}
-	}
-
-	if !foundSince {
+	sincePosition, ok := m.position(since)
+	if !ok {
 		err := fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, since)
 		return 0, nil, err
 	}
 
-	if !foundUntil {
+	untilPosition, ok := m.position(until)
+	if !ok {
 		err := fmt.Errorf("%w; revision: %q", ErrMigrationNotRegistered, until)
 		return 0, nil, err
 	}
 
+	pastMigrationCount := sincePosition + 1
+	result := []Migration{}
+	if untilPosition >= pastMigrationCount {
+		result = all[pastMigrationCount : untilPosition+1]
+	}
 	return pastMigrationCount, result, nil
 }
 
@@ -278,14 +437,10 @@ type describeMetadata struct {
 
 // Describe displays all of the registered migrations (with descriptions).
 func (m *Migrations) Describe(log PrintfReceiver) {
-	revisions := m.Revisions()
-
-	m.lock.Lock()
-	defer m.lock.Unlock()
 	dms := []describeMetadata{}
 	revisionWidth := 0
-	for _, revision := range revisions {
-		migration := m.sequence[revision]
+	for _, migration := range m.All() {
+		revision := migration.Revision
 		dms = append(
 			dms,
 			describeMetadata{
@@ -313,10 +468,20 @@ func (m *Migrations) Get(revision string) *Migration {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	migration, ok := m.sequence[revision]
+	position, ok := m.index[revision]
 	if ok {
+		migration := m.ordered[position]
 		return &migration
 	}
 
 	return nil
 }
+
+// position returns the position of a revision in the sequence, if present.
+func (m *Migrations) position(revision string) (int, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	position, ok := m.index[revision]
+	return position, ok
+}
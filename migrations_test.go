@@ -0,0 +1,267 @@
+package golembic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testMigration creates a migration with the given parents; this bypasses
+// `NewMigration()` since only the revisions matter for these tests.
+func testMigration(revision, previous string, merges ...string) Migration {
+	return Migration{Revision: revision, Previous: previous, Merges: merges}
+}
+
+// rawSequence creates a sequence from `ms` without enforcing the invariants
+// of `Register()`, so that invalid sequences (e.g. with cycles) can be
+// validated.
+func rawSequence(ms ...Migration) *Migrations {
+	index := map[string]int{}
+	for i, migration := range ms {
+		index[migration.Revision] = i
+	}
+
+	return &Migrations{ordered: ms, index: index}
+}
+
+// mergedSequence is a sequence that branches after `a` and is joined by the
+// merge migration `d`:
+//
+//	a -> b ------> d
+//	 \-> c -----/
+func mergedSequence() *Migrations {
+	return rawSequence(
+		testMigration("a", ""),
+		testMigration("b", "a"),
+		testMigration("c", "a"),
+		testMigration("d", "c", "b"),
+	)
+}
+
+func revisions(ms []Migration) []string {
+	result := []string{}
+	for _, migration := range ms {
+		result = append(result, migration.Revision)
+	}
+	return result
+}
+
+func TestMigrationsValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name       string
+		Migrations *Migrations
+		Errs       []error
+	}{
+		{
+			Name:       "linear",
+			Migrations: rawSequence(testMigration("a", ""), testMigration("b", "a"), testMigration("c", "b")),
+		},
+		{
+			Name:       "branch and merge",
+			Migrations: mergedSequence(),
+		},
+		{
+			Name:       "empty",
+			Migrations: rawSequence(),
+			Errs:       []error{ErrNoRoot},
+		},
+		{
+			Name:       "orphan",
+			Migrations: rawSequence(testMigration("a", ""), testMigration("b", "x")),
+			Errs:       []error{ErrOrphanedMigration, ErrUnreachableMigration},
+		},
+		{
+			Name:       "orphaned merge parent",
+			Migrations: rawSequence(testMigration("a", ""), testMigration("b", "a", "x")),
+			Errs:       []error{ErrOrphanedMigration},
+		},
+		{
+			Name:       "out of order",
+			Migrations: rawSequence(testMigration("a", ""), testMigration("c", "b"), testMigration("b", "a")),
+			Errs:       []error{ErrSequenceOutOfOrder},
+		},
+		{
+			Name:       "multiple roots",
+			Migrations: rawSequence(testMigration("a", ""), testMigration("b", "")),
+			Errs:       []error{ErrMultipleRoots},
+		},
+		{
+			Name:       "cycle without a root",
+			Migrations: rawSequence(testMigration("a", "b"), testMigration("b", "a")),
+			Errs:       []error{ErrMultipleRoots, ErrSequenceCycle, ErrSequenceOutOfOrder, ErrUnreachableMigration},
+		},
+		{
+			Name: "cycle through a merge",
+			Migrations: rawSequence(
+				testMigration("r", ""),
+				testMigration("a", "r", "c"),
+				testMigration("b", "a"),
+				testMigration("c", "b"),
+			),
+			Errs: []error{ErrSequenceCycle, ErrSequenceOutOfOrder},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.Migrations.Validate()
+			if len(tc.Errs) == 0 && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tc.Errs {
+				if !errors.Is(err, want) {
+					t.Fatalf("error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrationsHeads(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name       string
+		Migrations *Migrations
+		Want       []string
+	}{
+		{
+			Name:       "root only",
+			Migrations: rawSequence(testMigration("a", "")),
+			Want:       []string{"a"},
+		},
+		{
+			Name:       "branched",
+			Migrations: rawSequence(testMigration("a", ""), testMigration("b", "a"), testMigration("c", "a")),
+			Want:       []string{"b", "c"},
+		},
+		{
+			Name:       "merged",
+			Migrations: mergedSequence(),
+			Want:       []string{"d"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			got := revisions(tc.Migrations.Heads())
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("Heads() = %v, want %v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestMigrationsPending(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name    string
+		Applied []string
+		Want    []string
+	}{
+		{Name: "none applied", Applied: nil, Want: []string{"a", "b", "c", "d"}},
+		{Name: "one branch applied", Applied: []string{"a", "c"}, Want: []string{"b", "d"}},
+		{Name: "applied out of registration order", Applied: []string{"c", "a", "b"}, Want: []string{"d"}},
+		{Name: "all applied", Applied: []string{"a", "b", "c", "d"}, Want: []string{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			got := revisions(mergedSequence().Pending(tc.Applied))
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("Pending(%v) = %v, want %v", tc.Applied, got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestMigrationsPendingUntil(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name     string
+		Applied  []string
+		Revision string
+		Want     []string
+		Err      error
+	}{
+		{Name: "one branch", Applied: []string{"a"}, Revision: "c", Want: []string{"c"}},
+		{Name: "merge needs both branches", Applied: []string{"a"}, Revision: "d", Want: []string{"b", "c", "d"}},
+		{Name: "fresh database", Applied: nil, Revision: "b", Want: []string{"a", "b"}},
+		{Name: "other branch applied", Applied: []string{"a", "b"}, Revision: "d", Want: []string{"c", "d"}},
+		{Name: "already applied", Applied: []string{"a", "b", "c", "d"}, Revision: "d", Want: []string{}},
+		{Name: "not registered", Applied: nil, Revision: "x", Err: ErrMigrationNotRegistered},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			pending, err := mergedSequence().PendingUntil(tc.Applied, tc.Revision)
+			if tc.Err != nil {
+				if !errors.Is(err, tc.Err) {
+					t.Fatalf("error = %v, want %v", err, tc.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := revisions(pending)
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("PendingUntil(%v, %q) = %v, want %v", tc.Applied, tc.Revision, got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestMigrationsRegister(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name      string
+		Migration Migration
+		Err       error
+	}{
+		{Name: "branch", Migration: testMigration("e", "a")},
+		{Name: "merge", Migration: testMigration("e", "b", "c")},
+		{Name: "no previous", Migration: testMigration("e", ""), Err: ErrNoPrevious},
+		{Name: "previous not registered", Migration: testMigration("e", "x"), Err: ErrPreviousNotRegistered},
+		{Name: "merge not registered", Migration: testMigration("e", "a", "x"), Err: ErrPreviousNotRegistered},
+		{Name: "duplicate parent", Migration: testMigration("e", "a", "a"), Err: ErrDuplicateParent},
+		{Name: "already registered", Migration: testMigration("c", "b"), Err: ErrAlreadyRegistered},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations := mergedSequence()
+			err := migrations.Register(tc.Migration)
+			if tc.Err != nil {
+				if !errors.Is(err, tc.Err) {
+					t.Fatalf("error = %v, want %v", err, tc.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = migrations.Validate()
+			if err != nil {
+				t.Fatalf("sequence is invalid after Register(): %v", err)
+			}
+		})
+	}
+}
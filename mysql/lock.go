@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dhermes/golembic"
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.LockProvider`.
+var (
+	_ golembic.LockProvider = (*SQLProvider)(nil)
+)
+
+// AcquireLock acquires a named user-level lock via `GET_LOCK()`. The timeout
+// for `GET_LOCK()` is in seconds, so `timeout` must be exactly convertible to
+// seconds; a `timeout` of zero means waiting indefinitely.
+//
+// See: https://dev.mysql.com/doc/refman/8.0/en/locking-functions.html#function_get-lock
+func (*SQLProvider) AcquireLock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error {
+	seconds, err := golembic.ToRoundDuration(timeout, time.Second)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: A negative timeout means an infinite timeout for `GET_LOCK()`.
+	if seconds == 0 {
+		seconds = -1
+	}
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName(name), seconds).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return fmt.Errorf("%w; name: %q, timeout: %s", golembic.ErrLockNotAcquired, name, timeout)
+	}
+
+	return nil
+}
+
+// ReleaseLock releases a named user-level lock via `RELEASE_LOCK()`.
+func (*SQLProvider) ReleaseLock(ctx context.Context, conn *sql.Conn, name string) error {
+	var released sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", lockName(name)).Scan(&released)
+	if err != nil {
+		return err
+	}
+
+	if !released.Valid || released.Int64 != 1 {
+		return fmt.Errorf("%w; name: %q", golembic.ErrLockNotReleased, name)
+	}
+
+	return nil
+}
+
+// lockName namespaces a lock name so that it does not collide with other
+// user-level locks in the same MySQL server.
+func lockName(name string) string {
+	return "golembic:" + name
+}
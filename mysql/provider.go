@@ -11,8 +11,16 @@ import (
 
 // NOTE: Ensure that
 //   - `SQLProvider` satisfies `golembic.EngineProvider`.
+//   - `SQLProvider` satisfies `golembic.DropConstraintProvider`.
+//   - `SQLProvider` satisfies `golembic.TransactionalDDLProvider`.
+//   - `SQLProvider` satisfies `golembic.EngineNameProvider`.
+//   - `SQLProvider` satisfies `golembic.SchemaProvider`.
 var (
-	_ golembic.EngineProvider = (*SQLProvider)(nil)
+	_ golembic.EngineProvider           = (*SQLProvider)(nil)
+	_ golembic.DropConstraintProvider   = (*SQLProvider)(nil)
+	_ golembic.TransactionalDDLProvider = (*SQLProvider)(nil)
+	_ golembic.EngineNameProvider       = (*SQLProvider)(nil)
+	_ golembic.SchemaProvider           = (*SQLProvider)(nil)
 )
 
 // New creates a MySQL-specific database engine provider from some
@@ -55,6 +63,28 @@ func (*SQLProvider) NewCreateTableParameters() golembic.CreateTableParameters {
 	)
 }
 
+// DropUniqueConstraintSQL returns a statement that drops a `UNIQUE`
+// constraint; in MySQL, a `UNIQUE` constraint is dropped as an index.
+func (sp *SQLProvider) DropUniqueConstraintSQL(table, constraint string) string {
+	return fmt.Sprintf(
+		"ALTER TABLE %s DROP INDEX %s",
+		sp.QuoteIdentifier(table),
+		sp.QuoteIdentifier(constraint),
+	)
+}
+
+// EngineName returns the name of the database engine, i.e. "mysql".
+func (*SQLProvider) EngineName() string {
+	return "mysql"
+}
+
+// TransactionalDDL indicates that MySQL does **not** support running DDL
+// statements inside a transaction; a DDL statement such as `CREATE TABLE`
+// causes an implicit commit.
+func (*SQLProvider) TransactionalDDL() bool {
+	return false
+}
+
 // TimestampColumn produces a value that can be used for reading / writing
 // a `TIMESTAMP` column to a `time.Time` in MySQL.
 func (*SQLProvider) TimestampColumn() golembic.TimestampColumn {
@@ -102,3 +132,21 @@ func (sp *SQLProvider) TableExistsSQL() string {
 		sp.QuoteLiteral(sp.Config.DBName),
 	)
 }
+
+// ColumnExistsSQL returns a SQL query that can be used to determine if a
+// column exists in a table.
+func (sp *SQLProvider) ColumnExistsSQL() string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM information_schema.columns WHERE table_name = ? AND column_name = ? AND table_schema = %s",
+		sp.QuoteLiteral(sp.Config.DBName),
+	)
+}
+
+// IndexExistsSQL returns a SQL query that can be used to determine if an
+// index exists on a table; in MySQL, a `UNIQUE` constraint is an index.
+func (sp *SQLProvider) IndexExistsSQL() string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM information_schema.statistics WHERE table_name = ? AND index_name = ? AND table_schema = %s",
+		sp.QuoteLiteral(sp.Config.DBName),
+	)
+}
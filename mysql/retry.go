@@ -0,0 +1,35 @@
+package mysql
+
+import (
+	"regexp"
+
+	"github.com/dhermes/golembic"
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.RetryableErrorProvider`.
+var (
+	_ golembic.RetryableErrorProvider = (*SQLProvider)(nil)
+)
+
+var (
+	// retryableErrorNumber matches the error message produced for
+	// `ER_LOCK_WAIT_TIMEOUT` (1205) and `ER_LOCK_DEADLOCK` (1213), e.g.
+	// "Error 1205 (HY000): Lock wait timeout exceeded; try restarting transaction".
+	retryableErrorNumber = regexp.MustCompile(`\bError (1205|1213)\b`)
+)
+
+// IsRetryable indicates if `err` is a lock wait timeout (1205) or a deadlock
+// (1213).
+//
+// NOTE: This matches the error message rather than the error type so that
+// this package does not depend on a specific MySQL driver.
+//
+// See: https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+func (*SQLProvider) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return retryableErrorNumber.MatchString(err.Error())
+}
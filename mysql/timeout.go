@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dhermes/golembic"
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.TimeoutProvider`.
+var (
+	_ golembic.TimeoutProvider = (*SQLProvider)(nil)
+)
+
+// TimeoutSQL produces statements that override `lock_wait_timeout` (an exact
+// number of seconds) for a single migration along with a statement that
+// restores the current session value (read via `@@SESSION`). MySQL does not
+// have transaction scoped variables, so the session variable is restored
+// whether or not the migration runs in a transaction.
+//
+// MySQL does not have a statement timeout for DDL or DML statements (the
+// closest, `max_execution_time`, only applies to `SELECT` statements), so a
+// non-zero `statementTimeout` returns `golembic.ErrTimeoutUnsupported` rather
+// than being silently ignored.
+//
+// See:
+// - https://dev.mysql.com/doc/refman/8.0/en/server-system-variables.html#sysvar_lock_wait_timeout
+// - https://dev.mysql.com/doc/refman/8.0/en/server-system-variables.html#sysvar_max_execution_time
+func (*SQLProvider) TimeoutSQL(ctx context.Context, q golembic.RowQuerier, lockTimeout, statementTimeout time.Duration, _ bool) (set, reset []string, err error) {
+	if statementTimeout != 0 {
+		err = fmt.Errorf(
+			"%w; MySQL has no statement timeout for DDL or DML statements, statement timeout: %s",
+			golembic.ErrTimeoutUnsupported, statementTimeout,
+		)
+		return
+	}
+
+	if lockTimeout == 0 {
+		return
+	}
+
+	seconds, err := golembic.ToRoundDuration(lockTimeout, time.Second)
+	if err != nil {
+		return
+	}
+
+	var current int64
+	err = q.QueryRowContext(ctx, "SELECT @@SESSION.lock_wait_timeout").Scan(&current)
+	if err != nil {
+		return
+	}
+
+	set = []string{fmt.Sprintf("SET SESSION lock_wait_timeout = %d", seconds)}
+	reset = []string{fmt.Sprintf("SET SESSION lock_wait_timeout = %d", current)}
+	return
+}
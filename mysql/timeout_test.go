@@ -0,0 +1,47 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dhermes/golembic"
+	"github.com/dhermes/golembic/mysql"
+)
+
+func TestTimeoutSQLUnsupported(t *testing.T) {
+	t.Parallel()
+
+	// NOTE: None of these cases query the database, so no connection is
+	//       needed.
+	cases := []struct {
+		Name             string
+		LockTimeout      time.Duration
+		StatementTimeout time.Duration
+		Err              error
+	}{
+		{Name: "no timeouts"},
+		{Name: "lock timeout not in seconds", LockTimeout: 1500 * time.Millisecond, Err: golembic.ErrDurationConversion},
+		{Name: "statement timeout", StatementTimeout: time.Second, Err: golembic.ErrTimeoutUnsupported},
+		{Name: "both timeouts", LockTimeout: time.Second, StatementTimeout: time.Second, Err: golembic.ErrTimeoutUnsupported},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			sp := &mysql.SQLProvider{}
+			set, reset, err := sp.TimeoutSQL(context.Background(), nil, tc.LockTimeout, tc.StatementTimeout, true)
+			if tc.Err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("error = %v, want %v", err, tc.Err)
+			}
+			if len(set) > 0 || len(reset) > 0 {
+				t.Fatalf("TimeoutSQL() = %q, %q; want no statements", set, reset)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package golembic
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// NOTE: Ensure that
+//   - `NopObserver` satisfies `Observer`.
+var (
+	_ Observer = NopObserver{}
+)
+
+// NopObserver implements `Observer` and does nothing. It is intended to be
+// embedded in an observer that only needs some of the callbacks.
+type NopObserver struct{}
+
+// BeforeRun does nothing.
+func (NopObserver) BeforeRun(_ context.Context, _ []Migration) error {
+	return nil
+}
+
+// AfterRun does nothing.
+func (NopObserver) AfterRun(_ context.Context, _ *ApplyReport, _ error) {}
+
+// BeforeMigration does nothing.
+func (NopObserver) BeforeMigration(_ context.Context, _ Migration) error {
+	return nil
+}
+
+// AfterMigration does nothing.
+func (NopObserver) AfterMigration(_ context.Context, _ MigrationResult) {}
+
+// OnError does nothing.
+func (NopObserver) OnError(_ context.Context, _ Migration, _ error) {}
+
+// beforeRun invokes `BeforeRun` for every observer (in order) and returns
+// the observers that were invoked. If an observer vetoes the run, the
+// remaining observers are not invoked and `ErrVetoed` is returned.
+func (m *Manager) beforeRun(ctx context.Context, migrations []Migration) ([]Observer, error) {
+	for i, observer := range m.Observers {
+		err := observer.BeforeRun(ctx, migrations)
+		if err != nil {
+			err = fmt.Errorf("%w; %d migration(s); %v", ErrVetoed, len(migrations), err)
+			return m.Observers[:i+1], err
+		}
+	}
+
+	return m.Observers, nil
+}
+
+// afterRun invokes `AfterRun` for each of `observers` (in order), i.e. for
+// the observers that were invoked by `beforeRun()`.
+func (m *Manager) afterRun(ctx context.Context, observers []Observer, report *ApplyReport, err error) {
+	// NOTE: The report is finished (again) when it is returned to the caller,
+	//       e.g. by `withReport()` once the migration lock is released.
+	report.finish()
+	for _, observer := range observers {
+		observer.AfterRun(ctx, report, err)
+	}
+}
+
+// beforeMigration invokes `BeforeMigration` for every observer (in order)
+// and returns the observers that were invoked. If an observer vetoes the
+// migration, the remaining observers are not invoked and `ErrVetoed` is
+// returned.
+func (m *Manager) beforeMigration(ctx context.Context, migration Migration) ([]Observer, error) {
+	for i, observer := range m.Observers {
+		err := observer.BeforeMigration(ctx, migration)
+		if err != nil {
+			err = fmt.Errorf("%w; revision: %q; %v", ErrVetoed, migration.Revision, err)
+			return m.Observers[:i+1], err
+		}
+	}
+
+	return m.Observers, nil
+}
+
+// afterMigration invokes `AfterMigration` for each of `observers` (in order).
+func (m *Manager) afterMigration(ctx context.Context, observers []Observer, result MigrationResult) {
+	for _, observer := range observers {
+		observer.AfterMigration(ctx, result)
+	}
+}
+
+// onError invokes `OnError` for each of `observers` (in order).
+func (m *Manager) onError(ctx context.Context, observers []Observer, migration Migration, err error) {
+	for _, observer := range observers {
+		observer.OnError(ctx, migration, err)
+	}
+}
+
+// applyObserved invokes `applyMigrationWithRetry()`, notifying the observers
+// before and after the migration is applied.
+func (m *Manager) applyObserved(ctx context.Context, policy RetryPolicy, migration Migration, replaced []Migration) error {
+	started := time.Now().UTC()
+	observers, err := m.beforeMigration(ctx, migration)
+	if err == nil {
+		err = m.applyMigrationWithRetry(ctx, policy, migration, replaced)
+	}
+	if err != nil {
+		m.onError(ctx, observers, migration, err)
+		return err
+	}
+
+	m.afterMigration(ctx, observers, newMigrationResult(migration, StatusApplied, started, nil))
+	return nil
+}
@@ -0,0 +1,101 @@
+package golembic_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dhermes/golembic"
+	"github.com/dhermes/golembic/sqlite3"
+)
+
+// lockObserver implements `golembic.Observer` and records if the migration
+// lock is held (i.e. if there is a row in the SQLite lock table) when
+// `BeforeRun` is invoked.
+type lockObserver struct {
+	golembic.NopObserver
+	m      *golembic.Manager
+	locked []bool
+}
+
+func (lo *lockObserver) BeforeRun(ctx context.Context, _ []golembic.Migration) error {
+	pool, err := lo.m.EnsureConnectionPool(ctx)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE name = ?1", sqlite3.LockTable)
+	err = pool.QueryRowContext(ctx, query, lo.m.MetadataTable).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	lo.locked = append(lo.locked, count == 1)
+	return nil
+}
+
+func TestObserverBeforeRunHoldsLock(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name  string
+		Apply func(context.Context, *golembic.Manager) error
+	}{
+		{
+			Name: "Up",
+			Apply: func(ctx context.Context, m *golembic.Manager) error {
+				return m.Up(ctx)
+			},
+		},
+		{
+			Name: "UpOne",
+			Apply: func(ctx context.Context, m *golembic.Manager) error {
+				return m.UpOne(ctx)
+			},
+		},
+		{
+			Name: "UpTo",
+			Apply: func(ctx context.Context, m *golembic.Manager) error {
+				return m.UpTo(ctx, golembic.OptApplyRevision("a"))
+			},
+		},
+		{
+			Name: "ApplyMigration",
+			Apply: func(ctx context.Context, m *golembic.Manager) error {
+				err := m.EnsureMigrationsTable(ctx)
+				if err != nil {
+					return err
+				}
+
+				root, err := m.Sequence.Root()
+				if err != nil {
+					return err
+				}
+				return m.ApplyMigration(ctx, root)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations := newSequence(
+				t,
+				[]golembic.MigrationOption{golembic.OptRevision("a"), golembic.OptUpFromSQL("CREATE TABLE t1 (id INTEGER)")},
+			)
+			observer := &lockObserver{}
+			m := newSQLiteManager(t, migrations, golembic.OptManagerObserver(observer))
+			observer.m = m
+
+			err := tc.Apply(context.Background(), m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(observer.locked) != 1 || !observer.locked[0] {
+				t.Fatalf("migration lock held during BeforeRun: %v, want [true]", observer.locked)
+			}
+		})
+	}
+}
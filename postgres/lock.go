@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/dhermes/golembic"
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.LockProvider`.
+var (
+	_ golembic.LockProvider = (*SQLProvider)(nil)
+)
+
+// AcquireLock acquires a session level advisory lock via `pg_advisory_lock()`.
+// The wait is bounded by temporarily setting `lock_timeout` on the connection
+// (advisory locks respect `lock_timeout`); a `timeout` of zero disables the
+// timeout. The `statement_timeout` is set to the same value since the
+// connection's statement timeout (e.g. `DefaultStatementTimeout`) would
+// otherwise cancel the wait before `timeout` elapses.
+//
+// See: https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-ADVISORY-LOCKS
+func (sp *SQLProvider) AcquireLock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) (err error) {
+	ms, err := golembic.ToRoundDuration(timeout, time.Millisecond)
+	if err != nil {
+		return
+	}
+
+	value := sp.QuoteLiteral(fmt.Sprintf("%dms", ms))
+	for _, setting := range []string{"lock_timeout", "statement_timeout"} {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("SET %s TO %s", setting, value))
+		if err != nil {
+			return
+		}
+		defer func(setting string) {
+			// NOTE: `RESET` restores the value from the start of the session,
+			//       i.e. the value from the connection string (if any).
+			_, resetErr := conn.ExecContext(ctx, fmt.Sprintf("RESET %s", setting))
+			err = maybeWrap(err, resetErr, fmt.Sprintf("failed to reset %s", setting))
+		}(setting)
+	}
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey(name))
+	if err != nil {
+		err = fmt.Errorf("%w; name: %q, timeout: %s; %v", golembic.ErrLockNotAcquired, name, timeout, err)
+		return
+	}
+
+	return
+}
+
+// ReleaseLock releases a session level advisory lock via
+// `pg_advisory_unlock()`.
+func (*SQLProvider) ReleaseLock(ctx context.Context, conn *sql.Conn, name string) error {
+	released := false
+	err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockKey(name)).Scan(&released)
+	if err != nil {
+		return err
+	}
+
+	if !released {
+		return fmt.Errorf("%w; name: %q", golembic.ErrLockNotReleased, name)
+	}
+
+	return nil
+}
+
+// advisoryLockKey converts a lock name into the 64-bit integer key used
+// by PostgreSQL advisory lock functions.
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	// NOTE: `hash.Hash.Write()` never returns an error.
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// maybeWrap attempts to wrap a secondary error inside a primary one. If
+// one (or both) of the errors if `nil`, then no wrapping is necessary.
+//
+// This has been copied directly from `github.com/dhermes/golembic:sql.go`
+func maybeWrap(primary, secondary error, message string) error {
+	if primary == nil {
+		return secondary
+	}
+	if secondary == nil {
+		return primary
+	}
+
+	return fmt.Errorf("%w; %s: %v", primary, message, secondary)
+}
@@ -9,8 +9,14 @@ import (
 
 // NOTE: Ensure that
 //   - `SQLProvider` satisfies `golembic.EngineProvider`.
+//   - `SQLProvider` satisfies `golembic.TransactionalDDLProvider`.
+//   - `SQLProvider` satisfies `golembic.EngineNameProvider`.
+//   - `SQLProvider` satisfies `golembic.SchemaProvider`.
 var (
-	_ golembic.EngineProvider = (*SQLProvider)(nil)
+	_ golembic.EngineProvider           = (*SQLProvider)(nil)
+	_ golembic.TransactionalDDLProvider = (*SQLProvider)(nil)
+	_ golembic.EngineNameProvider       = (*SQLProvider)(nil)
+	_ golembic.SchemaProvider           = (*SQLProvider)(nil)
 )
 
 // New creates a PostgreSQL-specific database engine provider from some
@@ -56,6 +62,17 @@ func (*SQLProvider) NewCreateTableParameters() golembic.CreateTableParameters {
 	)
 }
 
+// EngineName returns the name of the database engine, i.e. "postgres".
+func (*SQLProvider) EngineName() string {
+	return "postgres"
+}
+
+// TransactionalDDL indicates that PostgreSQL supports running DDL statements
+// (e.g. `CREATE TABLE`) inside a transaction.
+func (*SQLProvider) TransactionalDDL() bool {
+	return true
+}
+
 // TimestampColumn produces a value that can be used for reading / writing
 // a `TIMESTAMP` column to a `time.Time` in PostgreSQL.
 func (*SQLProvider) TimestampColumn() golembic.TimestampColumn {
@@ -106,3 +123,26 @@ func (sp *SQLProvider) TableExistsSQL() string {
 
 	return "SELECT 1 FROM pg_catalog.pg_tables WHERE tablename = $1"
 }
+
+// ColumnExistsSQL returns a SQL query that can be used to determine if a
+// column exists in a table.
+func (sp *SQLProvider) ColumnExistsSQL() string {
+	query := "SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2"
+	if sp.Config.Schema != "" {
+		query += fmt.Sprintf(" AND table_schema = %s", sp.QuoteLiteral(sp.Config.Schema))
+	}
+
+	return query
+}
+
+// IndexExistsSQL returns a SQL query that can be used to determine if an
+// index exists on a table. A `UNIQUE` constraint is backed by an index with
+// the same name.
+func (sp *SQLProvider) IndexExistsSQL() string {
+	query := "SELECT 1 FROM pg_catalog.pg_indexes WHERE tablename = $1 AND indexname = $2"
+	if sp.Config.Schema != "" {
+		query += fmt.Sprintf(" AND schemaname = %s", sp.QuoteLiteral(sp.Config.Schema))
+	}
+
+	return query
+}
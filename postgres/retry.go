@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/dhermes/golembic"
+)
+
+const (
+	// sqlStateLockNotAvailable is the SQLSTATE for `lock_not_available`, e.g.
+	// when `lock_timeout` is exceeded.
+	sqlStateLockNotAvailable = "55P03"
+	// sqlStateSerializationFailure is the SQLSTATE for
+	// `serialization_failure`.
+	sqlStateSerializationFailure = "40001"
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.RetryableErrorProvider`.
+var (
+	_ golembic.RetryableErrorProvider = (*SQLProvider)(nil)
+)
+
+// sqlStateError is satisfied by the error types of PostgreSQL drivers, e.g.
+// `*pq.Error` from `github.com/lib/pq`.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// IsRetryable indicates if `err` is a lock timeout (55P03) or a serialization
+// failure (40001).
+//
+// See: https://www.postgresql.org/docs/current/errcodes-appendix.html
+func (*SQLProvider) IsRetryable(err error) bool {
+	var sse sqlStateError
+	if !errors.As(err, &sse) {
+		return false
+	}
+
+	code := sse.SQLState()
+	return code == sqlStateLockNotAvailable || code == sqlStateSerializationFailure
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dhermes/golembic"
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.TimeoutProvider`.
+//   - `SQLProvider` satisfies `golembic.ScopedTimeoutProvider`.
+var (
+	_ golembic.TimeoutProvider       = (*SQLProvider)(nil)
+	_ golembic.ScopedTimeoutProvider = (*SQLProvider)(nil)
+)
+
+// TimeoutSQL produces statements that override `lock_timeout` and
+// `statement_timeout` for a single migration along with statements that
+// restore the current values (read via `current_setting()`). In a
+// transaction, `SET LOCAL` is used so the override also ends with the
+// transaction, e.g. if the migration fails.
+func (sp *SQLProvider) TimeoutSQL(ctx context.Context, q golembic.RowQuerier, lockTimeout, statementTimeout time.Duration, transactional bool) (set, reset []string, err error) {
+	timeouts := []struct {
+		Name    string
+		Timeout time.Duration
+	}{
+		{Name: "lock_timeout", Timeout: lockTimeout},
+		{Name: "statement_timeout", Timeout: statementTimeout},
+	}
+
+	command := "SET"
+	if transactional {
+		command = "SET LOCAL"
+	}
+
+	for _, t := range timeouts {
+		if t.Timeout == 0 {
+			continue
+		}
+
+		var ms int64
+		ms, err = golembic.ToRoundDuration(t.Timeout, time.Millisecond)
+		if err != nil {
+			return
+		}
+
+		current := ""
+		err = q.QueryRowContext(ctx, "SELECT current_setting($1)", t.Name).Scan(&current)
+		if err != nil {
+			return
+		}
+
+		value := sp.QuoteLiteral(fmt.Sprintf("%dms", ms))
+		set = append(set, fmt.Sprintf("%s %s TO %s", command, t.Name, value))
+		reset = append(reset, fmt.Sprintf("%s %s TO %s", command, t.Name, sp.QuoteLiteral(current)))
+	}
+
+	return
+}
+
+// TransactionScopedTimeouts indicates that the statements produced by
+// `TimeoutSQL()` in a transaction are scoped to the transaction, i.e. they
+// use `SET LOCAL`.
+func (*SQLProvider) TransactionScopedTimeouts() bool {
+	return true
+}
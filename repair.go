@@ -0,0 +1,182 @@
+package golembic
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RepairAction describes how a dirty migration should be resolved by
+// `Manager.Repair()`.
+type RepairAction string
+
+const (
+	// RepairComplete marks a dirty migration as complete, i.e. the operator
+	// has confirmed that the migration was fully applied (or has finished
+	// applying it by hand).
+	RepairComplete RepairAction = "complete"
+	// RepairRevert removes a dirty migration from the migrations metadata
+	// table, i.e. the operator has confirmed that the migration was not
+	// applied (or has undone it by hand) so it will be run again by the next
+	// "up" command.
+	RepairRevert RepairAction = "revert"
+)
+
+// validate checks that the action is one of the known actions.
+func (ra RepairAction) validate() error {
+	if ra == RepairComplete || ra == RepairRevert {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%w; action: %q, must be one of %q or %q",
+		ErrInvalidRepairAction, ra, RepairComplete, RepairRevert,
+	)
+}
+
+// Repair resolves a dirty migration, i.e. a non-transactional migration that
+// was started but did not complete. Since golembic can't determine how much
+// of the migration was applied, an operator must inspect the database and
+// decide how to resolve it via `action`. The `revision` must match the dirty
+// migration; this guards against resolving the wrong migration. The migration
+// lock is held while repairing.
+//
+// If the dirty migration is a baseline that was applied to a fresh database,
+// reverting it also removes the migrations that were stamped along with it.
+func (m *Manager) Repair(ctx context.Context, revision string, action RepairAction) error {
+	err := action.validate()
+	if err != nil {
+		return err
+	}
+
+	return m.withMigrationLock(ctx, func() error {
+		return m.repair(ctx, revision, action)
+	})
+}
+
+// repair is the unlocked form of `Repair()`.
+func (m *Manager) repair(ctx context.Context, revision string, action RepairAction) (err error) {
+	err = m.EnsureMigrationsTable(ctx)
+	if err != nil {
+		return
+	}
+
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	dirty, err := m.dirtyMigrations(ctx, tx)
+	if err != nil {
+		return
+	}
+
+	index := -1
+	for i, migration := range dirty {
+		if migration.Revision == revision {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		err = fmt.Errorf("%w; revision: %q", ErrNotDirty, revision)
+		return
+	}
+
+	stored := dirty[index]
+	if action == RepairComplete {
+		m.printf("Marking %s as complete", revision)
+		err = m.clearDirty(ctx, tx, stored)
+	} else {
+		err = m.revertDirty(ctx, tx, stored)
+	}
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// revertDirty removes a dirty migration from the migrations metadata table.
+// For a baseline, the rows for the migrations it replaced are removed as well.
+func (m *Manager) revertDirty(ctx context.Context, tx *sql.Tx, stored Migration) error {
+	migration := m.Sequence.Get(stored.Revision)
+	if migration == nil || !migration.Baseline {
+		m.printf("Reverting %s", stored.Revision)
+		return m.DeleteMigration(ctx, tx, stored)
+	}
+
+	// NOTE: A baseline is only applied (rather than stamped) to a fresh
+	//       database, so every row in the table was stamped along with it.
+	m.printf("Reverting baseline %s (and the migrations it replaced)", stored.Revision)
+	statement := fmt.Sprintf("DELETE FROM %s", m.Provider.QuoteIdentifier(m.MetadataTable))
+	_, err := tx.ExecContext(ctx, statement)
+	return err
+}
+
+// ensureClean creates a transaction that makes sure there is no dirty row in
+// the migrations metadata table.
+func (m *Manager) ensureClean(ctx context.Context) (err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	err = m.checkDirty(ctx, tx)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// checkDirty returns `ErrMigrationDirty` if there is a dirty row in the
+// migrations metadata table.
+func (m *Manager) checkDirty(ctx context.Context, tx *sql.Tx) error {
+	dirty, err := m.dirtyMigrations(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	return dirtyError(dirty[0].Revision)
+}
+
+// dirtyMigrations reads the dirty rows from the migrations metadata table.
+//
+// NOTE: This assumes, but does not check, that the migrations metadata table
+// exists.
+func (m *Manager) dirtyMigrations(ctx context.Context, tx *sql.Tx) ([]Migration, error) {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE dirty = %s ORDER BY serial_id ASC",
+		metadataColumns,
+		m.Provider.QuoteIdentifier(m.MetadataTable),
+		m.Provider.QueryParameter(1),
+	)
+	tc := m.Provider.TimestampColumn()
+	return readAllMigration(ctx, tx, query, tc, true)
+}
+
+// dirtyError returns `ErrMigrationDirty` for a dirty revision, including a
+// hint on how to resolve it.
+func dirtyError(revision string) error {
+	return fmt.Errorf(
+		"%w; revision: %q must be repaired (e.g. via `Repair()`) before continuing",
+		ErrMigrationDirty, revision,
+	)
+}
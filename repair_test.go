@@ -0,0 +1,133 @@
+package golembic_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dhermes/golembic"
+)
+
+func TestRepair(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name     string
+		Baseline bool
+		Revision string
+		Action   golembic.RepairAction
+		Err      error
+		// Want maps each revision in the metadata table to its `dirty` flag
+		// after the repair.
+		Want map[string]bool
+		// Runs is the number of times `b` has run once `Up()` succeeds after
+		// the repair.
+		Runs int
+	}{
+		{
+			Name:     "complete",
+			Revision: "b",
+			Action:   golembic.RepairComplete,
+			Want:     map[string]bool{"a": false, "b": false},
+			Runs:     1,
+		},
+		{
+			Name:     "revert",
+			Revision: "b",
+			Action:   golembic.RepairRevert,
+			Want:     map[string]bool{"a": false},
+			Runs:     2,
+		},
+		{
+			Name:     "revert baseline",
+			Baseline: true,
+			Revision: "b",
+			Action:   golembic.RepairRevert,
+			Want:     map[string]bool{},
+			Runs:     2,
+		},
+		{
+			Name:     "not dirty",
+			Revision: "a",
+			Action:   golembic.RepairComplete,
+			Err:      golembic.ErrNotDirty,
+			Want:     map[string]bool{"a": false, "b": true},
+		},
+		{
+			Name:     "invalid action",
+			Revision: "b",
+			Action:   "rerun",
+			Err:      golembic.ErrInvalidRepairAction,
+			Want:     map[string]bool{"a": false, "b": true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			// NOTE: `b` fails (and is left dirty) the first time it runs.
+			runs := 0
+			migrations := newSequence(
+				t,
+				[]golembic.MigrationOption{golembic.OptRevision("a"), golembic.OptUpFromSQL("CREATE TABLE t1 (id INTEGER)")},
+				[]golembic.MigrationOption{
+					golembic.OptRevision("b"),
+					golembic.OptBaseline(tc.Baseline),
+					golembic.OptUpConn(func(ctx context.Context, conn *sql.Conn) error {
+						runs++
+						if runs == 1 {
+							return errors.New("interrupted")
+						}
+
+						_, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS t2 (id INTEGER)")
+						return err
+					}),
+				},
+				[]golembic.MigrationOption{golembic.OptRevision("c"), golembic.OptUpFromSQL("CREATE TABLE t3 (id INTEGER)")},
+			)
+			m := newSQLiteManager(t, migrations)
+			ctx := context.Background()
+
+			err := m.Up(ctx)
+			if !errors.Is(err, golembic.ErrMigrationDirty) {
+				t.Fatalf("error = %v, want %v", err, golembic.ErrMigrationDirty)
+			}
+			err = m.Up(ctx)
+			if !errors.Is(err, golembic.ErrMigrationDirty) {
+				t.Fatalf("error = %v, want %v before repairing", err, golembic.ErrMigrationDirty)
+			}
+
+			err = m.Repair(ctx, tc.Revision, tc.Action)
+			if tc.Err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("error = %v, want %v", err, tc.Err)
+			}
+
+			got := appliedRows(t, m)
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("applied = %v, want %v", got, tc.Want)
+			}
+			if tc.Err != nil {
+				return
+			}
+
+			err = m.Up(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if runs != tc.Runs {
+				t.Fatalf("b ran %d time(s), want %d", runs, tc.Runs)
+			}
+			got = appliedRows(t, m)
+			want := map[string]bool{"a": false, "b": false, "c": false}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("applied = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package golembic
+
+import (
+	"errors"
+	"time"
+)
+
+// MigrationStatus describes the outcome of a migration during an "up"
+// command.
+type MigrationStatus string
+
+const (
+	// StatusApplied indicates that a migration was applied.
+	StatusApplied MigrationStatus = "applied"
+	// StatusFailed indicates that a migration was attempted but failed.
+	StatusFailed MigrationStatus = "failed"
+	// StatusSkipped indicates that a migration was not attempted because an
+	// earlier migration failed.
+	StatusSkipped MigrationStatus = "skipped"
+	// StatusStamped indicates that a migration was recorded as applied
+	// without being run because of a baseline.
+	StatusStamped MigrationStatus = "stamped"
+	// StatusDirty indicates that a non-transactional migration was started
+	// but did not complete (or could not be marked as complete), so it is
+	// recorded as dirty in the migrations metadata table.
+	StatusDirty MigrationStatus = "dirty"
+	// StatusRolledBack indicates that a migration was applied, but then
+	// rolled back because a later migration in the same transaction failed.
+	StatusRolledBack MigrationStatus = "rolled_back"
+	// StatusPlanned indicates that a migration would have been applied, but
+	// was not because of a dry run.
+	StatusPlanned MigrationStatus = "planned"
+)
+
+// outcomeStatus determines the status of a migration that was attempted,
+// based on the error (if any) returned when applying it. A migration that was
+// vetoed by an observer is considered skipped.
+func outcomeStatus(err error) MigrationStatus {
+	if err == nil {
+		return StatusApplied
+	}
+	if errors.Is(err, ErrMigrationDirty) {
+		return StatusDirty
+	}
+	if errors.Is(err, ErrVetoed) {
+		return StatusSkipped
+	}
+	return StatusFailed
+}
+
+// MigrationResult describes the outcome of a single migration during an "up"
+// command.
+type MigrationResult struct {
+	Revision    string
+	Description string
+	Milestone   bool
+	Status      MigrationStatus
+	// Started and Finished are only set for migrations that were attempted,
+	// i.e. `StatusApplied`, `StatusFailed`, `StatusDirty` or
+	// `StatusRolledBack` (or a baseline that was `StatusStamped`).
+	Started  time.Time
+	Finished time.Time
+	Duration time.Duration
+	// Err is the error that caused a migration to fail, if any.
+	Err error
+}
+
+// ApplyReport describes the outcome of an "up" command, e.g. `UpWithReport()`.
+// It lists every migration that was selected to run (in order) along with
+// the outcome of each.
+type ApplyReport struct {
+	Started    time.Time
+	Finished   time.Time
+	Duration   time.Duration
+	DryRun     bool
+	Migrations []MigrationResult
+}
+
+// newApplyReport creates a new report that has been started.
+func newApplyReport(ac *ApplyConfig) *ApplyReport {
+	return &ApplyReport{Started: time.Now().UTC(), DryRun: ac.DryRun}
+}
+
+// finish marks a report as finished.
+func (ar *ApplyReport) finish() {
+	ar.Finished = time.Now().UTC()
+	ar.Duration = ar.Finished.Sub(ar.Started)
+}
+
+// record adds the outcome of a migration to the report.
+func (ar *ApplyReport) record(migration Migration, status MigrationStatus, started time.Time, err error) {
+	ar.Migrations = append(ar.Migrations, newMigrationResult(migration, status, started, err))
+}
+
+// recordOutcome adds the outcome of a migration that was attempted to the
+// report, with the status determined by `outcomeStatus()`.
+func (ar *ApplyReport) recordOutcome(migration Migration, started time.Time, err error) {
+	status := outcomeStatus(err)
+	if status == StatusSkipped {
+		started = time.Time{}
+	}
+
+	ar.record(migration, status, started, err)
+}
+
+// newMigrationResult describes the outcome of a migration. If `started` is
+// set, the migration is assumed to have finished just now.
+func newMigrationResult(migration Migration, status MigrationStatus, started time.Time, err error) MigrationResult {
+	result := MigrationResult{
+		Revision:    migration.Revision,
+		Description: migration.Description,
+		Milestone:   migration.Milestone,
+		Status:      status,
+		Err:         err,
+	}
+	if !started.IsZero() {
+		result.Started = started
+		result.Finished = time.Now().UTC()
+		result.Duration = result.Finished.Sub(result.Started)
+	}
+
+	return result
+}
+
+// rollBack marks every result (starting at `first`) that was applied or
+// stamped as rolled back. This is intended to be used when a batch of
+// migrations applied in a single transaction fails.
+func (ar *ApplyReport) rollBack(first int) {
+	for i := first; i < len(ar.Migrations); i++ {
+		status := ar.Migrations[i].Status
+		if status == StatusApplied || status == StatusStamped {
+			ar.Migrations[i].Status = StatusRolledBack
+		}
+	}
+}
+
+// Applied returns the results for the migrations that were applied.
+func (ar *ApplyReport) Applied() []MigrationResult {
+	return ar.filter(StatusApplied)
+}
+
+// Failed returns the results for the migrations that failed.
+func (ar *ApplyReport) Failed() []MigrationResult {
+	return ar.filter(StatusFailed)
+}
+
+// Skipped returns the results for the migrations that were skipped.
+func (ar *ApplyReport) Skipped() []MigrationResult {
+	return ar.filter(StatusSkipped)
+}
+
+func (ar *ApplyReport) filter(status MigrationStatus) []MigrationResult {
+	result := []MigrationResult{}
+	for _, mr := range ar.Migrations {
+		if mr.Status == status {
+			result = append(result, mr)
+		}
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package golembic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy describes how a migration that fails with a retryable error
+// is retried. The zero value means a migration is attempted exactly once.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a migration will be
+	// attempted (including the first attempt); 0 is treated the same as 1.
+	MaxAttempts int
+	// Backoff is the time to wait before the first retry; the wait doubles
+	// for each subsequent retry.
+	Backoff time.Duration
+	// MaxBackoff is an optional upper bound on the (doubled) wait before
+	// jitter is added; if zero, the wait is only bounded by the largest
+	// `time.Duration`.
+	MaxBackoff time.Duration
+	// Jitter is the maximum (random) amount of time added to each wait, so
+	// that concurrent processes don't retry in lockstep.
+	Jitter time.Duration
+}
+
+func (rp RetryPolicy) validate() error {
+	if rp.MaxAttempts < 0 || rp.Backoff < 0 || rp.MaxBackoff < 0 || rp.Jitter < 0 {
+		err := fmt.Errorf(
+			"%w; max attempts: %d, backoff: %s, max backoff: %s, jitter: %s",
+			ErrInvalidRetryPolicy, rp.MaxAttempts, rp.Backoff, rp.MaxBackoff, rp.Jitter,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// attempts returns the maximum number of attempts, which is always at least 1.
+func (rp RetryPolicy) attempts() int {
+	if rp.MaxAttempts < 1 {
+		return 1
+	}
+	return rp.MaxAttempts
+}
+
+// wait returns the time to wait after the (1-indexed) `attempt` fails. The
+// backoff is doubled (rather than shifted) one retry at a time so that it
+// saturates at `MaxBackoff` (or the largest `time.Duration`) instead of
+// overflowing.
+func (rp RetryPolicy) wait(attempt int) time.Duration {
+	limit := time.Duration(math.MaxInt64)
+	if rp.MaxBackoff > 0 {
+		limit = rp.MaxBackoff
+	}
+
+	wait := min(rp.Backoff, limit)
+	for i := 1; i < attempt && wait < limit; i++ {
+		if wait > limit/2 {
+			wait = limit
+			break
+		}
+		wait *= 2
+	}
+
+	if rp.Jitter > 0 {
+		jitter := rand.N(rp.Jitter)
+		if wait > math.MaxInt64-jitter {
+			return time.Duration(math.MaxInt64)
+		}
+		wait += jitter
+	}
+	return wait
+}
+
+// isRetryable determines if an error is retryable according to the provider.
+func (m *Manager) isRetryable(err error) bool {
+	rep, ok := m.Provider.(RetryableErrorProvider)
+	if !ok {
+		return false
+	}
+
+	return rep.IsRetryable(err)
+}
+
+// applyMigrationWithRetry invokes `applyMigration()` and retries (according
+// to `policy`) if it fails with a retryable error. Only transactional
+// migrations are retried since a failed transaction leaves no trace; a
+// non-transactional migration may have been partially applied. The outcome
+// (after all attempts) is emitted as a structured log record.
+func (m *Manager) applyMigrationWithRetry(ctx context.Context, policy RetryPolicy, migration Migration, replaced []Migration) (err error) {
+	started := time.Now()
+	defer func() {
+		m.logOutcome(ctx, migration, outcomeStatus(err), started, err)
+	}()
+
+	attempts := policy.attempts()
+	for attempt := 1; ; attempt++ {
+		err = m.applyMigration(ctx, migration, replaced)
+		if err == nil || attempt >= attempts {
+			return
+		}
+		if !migration.Transactional() || !m.isRetryable(err) {
+			return
+		}
+
+		err = m.waitToRetry(ctx, policy, attempt, migration.Revision, err)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// applySingleTransactionWithRetry invokes `applySingleTransaction()` and
+// retries the whole transaction (according to `policy`) if it fails with a
+// retryable error. The outcomes recorded in `report` by a failed attempt are
+// discarded before retrying.
+func (m *Manager) applySingleTransactionWithRetry(ctx context.Context, policy RetryPolicy, migrations []Migration, stamped []bool, report *ApplyReport) (err error) {
+	first := len(report.Migrations)
+	attempts := policy.attempts()
+	for attempt := 1; ; attempt++ {
+		err = m.applySingleTransaction(ctx, migrations, stamped, report)
+		if err == nil || attempt >= attempts || !m.isRetryable(err) {
+			return
+		}
+
+		label := fmt.Sprintf("%d migration(s) in a single transaction", len(migrations))
+		err = m.waitToRetry(ctx, policy, attempt, label, err)
+		if err != nil {
+			return
+		}
+		report.Migrations = report.Migrations[:first]
+	}
+}
+
+// waitToRetry waits (according to `policy`) after the (1-indexed) `attempt`
+// fails with `err`. If the context is cancelled while waiting, `err` is
+// returned (wrapped with the cancellation); otherwise this returns `nil`.
+func (m *Manager) waitToRetry(ctx context.Context, policy RetryPolicy, attempt int, label string, err error) error {
+	wait := policy.wait(attempt)
+	m.printf(
+		"Attempt %d of %d for %s failed; retrying in %s: %v",
+		attempt, policy.attempts(), label, wait, err,
+	)
+
+	timer := time.NewTimer(wait)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return maybeWrap(err, ctx.Err(), "retry cancelled")
+	case <-timer.C:
+		return nil
+	}
+}
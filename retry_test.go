@@ -0,0 +1,140 @@
+package golembic
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyWait(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name    string
+		Policy  RetryPolicy
+		Attempt int
+		Want    time.Duration
+	}{
+		{Name: "zero value", Policy: RetryPolicy{}, Attempt: 1, Want: 0},
+		{Name: "first retry", Policy: RetryPolicy{Backoff: time.Second}, Attempt: 1, Want: time.Second},
+		{Name: "doubles", Policy: RetryPolicy{Backoff: time.Second}, Attempt: 4, Want: 8 * time.Second},
+		{
+			Name:    "clamped to max backoff",
+			Policy:  RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second},
+			Attempt: 4,
+			Want:    5 * time.Second,
+		},
+		{
+			Name:    "backoff above max backoff",
+			Policy:  RetryPolicy{Backoff: time.Minute, MaxBackoff: 5 * time.Second},
+			Attempt: 1,
+			Want:    5 * time.Second,
+		},
+		{
+			Name:    "saturates instead of overflowing",
+			Policy:  RetryPolicy{Backoff: time.Second},
+			Attempt: 100,
+			Want:    time.Duration(math.MaxInt64),
+		},
+		{
+			Name:    "shift would wrap to zero",
+			Policy:  RetryPolicy{Backoff: time.Second},
+			Attempt: 65,
+			Want:    time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.Policy.wait(tc.Attempt)
+			if got != tc.Want {
+				t.Fatalf("wait(%d) = %s, want %s", tc.Attempt, got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyWaitJitter(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name    string
+		Policy  RetryPolicy
+		Attempt int
+		Min     time.Duration
+		Max     time.Duration
+	}{
+		{
+			Name:    "jitter added",
+			Policy:  RetryPolicy{Backoff: time.Second, Jitter: 500 * time.Millisecond},
+			Attempt: 2,
+			Min:     2 * time.Second,
+			Max:     2500 * time.Millisecond,
+		},
+		{
+			Name:    "jitter added after max backoff",
+			Policy:  RetryPolicy{Backoff: time.Second, MaxBackoff: 3 * time.Second, Jitter: time.Second},
+			Attempt: 10,
+			Min:     3 * time.Second,
+			Max:     4 * time.Second,
+		},
+		{
+			Name:    "jitter saturates",
+			Policy:  RetryPolicy{Backoff: time.Second, Jitter: time.Second},
+			Attempt: 100,
+			Min:     time.Duration(math.MaxInt64),
+			Max:     time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			for i := 0; i < 100; i++ {
+				got := tc.Policy.wait(tc.Attempt)
+				if got < tc.Min || got > tc.Max {
+					t.Fatalf("wait(%d) = %s, want in [%s, %s]", tc.Attempt, got, tc.Min, tc.Max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name   string
+		Policy RetryPolicy
+		Valid  bool
+	}{
+		{Name: "zero value", Policy: RetryPolicy{}, Valid: true},
+		{
+			Name:   "all set",
+			Policy: RetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Minute, Jitter: time.Second},
+			Valid:  true,
+		},
+		{Name: "negative attempts", Policy: RetryPolicy{MaxAttempts: -1}},
+		{Name: "negative backoff", Policy: RetryPolicy{Backoff: -time.Second}},
+		{Name: "negative max backoff", Policy: RetryPolicy{MaxBackoff: -time.Second}},
+		{Name: "negative jitter", Policy: RetryPolicy{Jitter: -time.Second}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			err := OptApplyRetry(tc.Policy)(&ApplyConfig{})
+			if tc.Valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.Valid && !errors.Is(err, ErrInvalidRetryPolicy) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidRetryPolicy)
+			}
+		})
+	}
+}
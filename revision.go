@@ -0,0 +1,24 @@
+package golembic
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// revisionBytes is the number of random bytes in a revision generated by
+	// `NewRevision()`; hex encoded this is 12 characters.
+	revisionBytes = 6
+)
+
+// NewRevision generates a new random revision, e.g. `c9b52448285b`, that can
+// be used for a new migration.
+func NewRevision() (string, error) {
+	b := make([]byte, revisionBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,109 @@
+package golembic
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// checkSingleTransaction makes sure that `migrations` can be applied in a
+// single transaction, i.e. that the provider supports transactional DDL and
+// that every migration that will be run (rather than stamped) is
+// transactional.
+func (m *Manager) checkSingleTransaction(migrations []Migration, stamped []bool) error {
+	tdp, ok := m.Provider.(TransactionalDDLProvider)
+	if !ok || !tdp.TransactionalDDL() {
+		err := fmt.Errorf(
+			"%w; provider does not support transactional DDL",
+			ErrSingleTransactionUnsupported,
+		)
+		return err
+	}
+
+	for i, migration := range migrations {
+		if stamped[i] || migration.Transactional() {
+			continue
+		}
+
+		err := fmt.Errorf(
+			"%w; revision %s is not transactional",
+			ErrSingleTransactionUnsupported, migration.Revision,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// applySingleTransaction applies (or stamps) `migrations` (in order) in a
+// single transaction and records the outcome of each in `report`. If a
+// migration fails, the migrations before it are recorded as rolled back and
+// the remaining migrations are recorded as skipped. The observers on the
+// manager are notified after the transaction is committed; if it is rolled
+// back, `OnError` is invoked for every migration that was run (by the
+// observers that were notified before it was run).
+func (m *Manager) applySingleTransaction(ctx context.Context, migrations []Migration, stamped []bool, report *ApplyReport) (err error) {
+	first := len(report.Migrations)
+	run := []Migration{}
+	notified := [][]Observer{}
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+		if err != nil {
+			report.rollBack(first)
+			for j, migration := range run {
+				m.onError(ctx, notified[j], migration, err)
+			}
+			return
+		}
+
+		for _, result := range report.Migrations[first:] {
+			if result.Status == StatusApplied {
+				m.afterMigration(ctx, m.Observers, result)
+			}
+		}
+	}()
+
+	pool, err := m.EnsureConnectionPool(ctx)
+	if err != nil {
+		return
+	}
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	m.printf("Applying %d migration(s) in a single transaction", len(migrations))
+	for i, migration := range migrations {
+		started := time.Now().UTC()
+		status := StatusApplied
+		if stamped[i] {
+			status = StatusStamped
+			err = m.insertStamped(ctx, tx, []Migration{migration})
+		} else {
+			var observers []Observer
+			observers, err = m.beforeMigration(ctx, migration)
+			run = append(run, migration)
+			notified = append(notified, observers)
+			if err == nil {
+				err = m.invokeUp(ctx, pool, tx, migration)
+			}
+		}
+		if err != nil {
+			m.logOutcome(ctx, migration, outcomeStatus(err), started, err)
+			report.recordOutcome(migration, started, err)
+			for _, skipped := range migrations[i+1:] {
+				report.record(skipped, StatusSkipped, time.Time{}, nil)
+			}
+			return
+		}
+
+		m.logOutcome(ctx, migration, status, started, nil)
+		report.record(migration, status, started, nil)
+	}
+
+	err = tx.Commit()
+	return
+}
@@ -0,0 +1,173 @@
+package golembic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// attrRevision is the structured logging key for a migration revision.
+	attrRevision = "revision"
+	// attrDescription is the structured logging key for a migration
+	// description.
+	attrDescription = "description"
+	// attrMilestone is the structured logging key for the milestone flag of a
+	// migration.
+	attrMilestone = "milestone"
+	// attrDuration is the structured logging key for the time it took to
+	// apply a migration.
+	attrDuration = "duration"
+	// attrEngine is the structured logging key for the database engine.
+	attrEngine = "engine"
+	// attrOutcome is the structured logging key for the outcome of a
+	// migration, e.g. "applied" or "pending".
+	attrOutcome = "outcome"
+	// attrPending is the structured logging key for the number of migrations
+	// that have not yet been applied.
+	attrPending = "pending"
+	// attrError is the structured logging key for an error.
+	attrError = "error"
+)
+
+// NOTE: Ensure that
+//   - `printfHandler` satisfies `slog.Handler`.
+//   - `slogPrintf` satisfies `PrintfReceiver`.
+var (
+	_ slog.Handler   = (*printfHandler)(nil)
+	_ PrintfReceiver = (*slogPrintf)(nil)
+)
+
+// printfHandler implements `slog.Handler` by displaying the message of each
+// record via a `PrintfReceiver`; this is used when a manager has no
+// structured logger so that the output matches the output from before
+// structured logging was added. Attributes are dropped, with the exception of
+// an error attribute, which is displayed (indented) on its own line. Records
+// below `slog.LevelInfo` are dropped.
+type printfHandler struct {
+	log PrintfReceiver
+}
+
+func (ph *printfHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (ph *printfHandler) Handle(_ context.Context, r slog.Record) error {
+	_, err := ph.log.Printf("%s", r.Message)
+	if err != nil {
+		return err
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key != attrError {
+			return true
+		}
+
+		_, err = ph.log.Printf("  %s", a.Value)
+		return false
+	})
+	return err
+}
+
+func (ph *printfHandler) WithAttrs(_ []slog.Attr) slog.Handler {
+	return ph
+}
+
+func (ph *printfHandler) WithGroup(_ string) slog.Handler {
+	return ph
+}
+
+// slogPrintf implements `PrintfReceiver` by emitting an (informational)
+// record with the formatted message to a structured logger. This is used so
+// that messages without structured attributes (e.g. from `Describe()`) are
+// sent to the same structured logger.
+type slogPrintf struct {
+	logger *slog.Logger
+}
+
+func (sp *slogPrintf) Printf(format string, a ...interface{}) (n int, err error) {
+	message := fmt.Sprintf(format, a...)
+	sp.logger.Info(message)
+	return len(message), nil
+}
+
+// printfReceiver returns the logger interface for messages without
+// structured attributes. If a structured logger is set, it takes precedence
+// over `m.Log` (however either was set) so that the output isn't split
+// between `Log` and `Slog`.
+func (m *Manager) printfReceiver() PrintfReceiver {
+	if m.Slog != nil {
+		return &slogPrintf{logger: m.Slog}
+	}
+
+	return m.Log
+}
+
+// printf displays a message without structured attributes; see
+// `printfReceiver()`.
+func (m *Manager) printf(format string, a ...interface{}) {
+	m.printfReceiver().Printf(format, a...)
+}
+
+// logger returns the structured logger for the manager. If none is set, the
+// records are displayed via `m.Log`.
+func (m *Manager) logger() *slog.Logger {
+	if m.Slog != nil {
+		return m.Slog
+	}
+
+	return slog.New(&printfHandler{log: m.Log})
+}
+
+// engineName determines the name of the database engine for structured
+// logging. If the provider does not satisfy `EngineNameProvider`, the type
+// of the provider is used.
+func (m *Manager) engineName() string {
+	enp, ok := m.Provider.(EngineNameProvider)
+	if ok {
+		return enp.EngineName()
+	}
+
+	return fmt.Sprintf("%T", m.Provider)
+}
+
+// logMigration emits a structured log record for a migration, with the
+// revision, description, milestone flag and engine as attributes (along with
+// any `extra` attributes).
+func (m *Manager) logMigration(ctx context.Context, level slog.Level, message string, migration Migration, extra ...slog.Attr) {
+	attrs := []slog.Attr{
+		slog.String(attrRevision, migration.Revision),
+		slog.String(attrDescription, migration.Description),
+		slog.Bool(attrMilestone, migration.Milestone),
+		slog.String(attrEngine, m.engineName()),
+	}
+	m.logger().LogAttrs(ctx, level, message, append(attrs, extra...)...)
+}
+
+// logOutcome emits a structured log record with the outcome of applying a
+// migration. These records are only emitted to a structured logger (i.e.
+// `Slog`) so that the output of a `PrintfReceiver` is unchanged.
+func (m *Manager) logOutcome(ctx context.Context, migration Migration, status MigrationStatus, started time.Time, err error) {
+	if m.Slog == nil {
+		return
+	}
+
+	extra := []slog.Attr{
+		slog.String(attrOutcome, string(status)),
+		slog.Duration(attrDuration, time.Since(started)),
+	}
+	if err != nil {
+		extra = append(extra, slog.String(attrError, err.Error()))
+	}
+
+	message := fmt.Sprintf("Migration %s %s", migration.Revision, status)
+	m.logMigration(ctx, slog.LevelInfo, message, migration, extra...)
+}
+
+// ignoreInDevelopmentMode emits a warning for an error that is ignored
+// because the manager is in development mode.
+func (m *Manager) ignoreInDevelopmentMode(ctx context.Context, err error, attrs ...slog.Attr) {
+	attrs = append(attrs, slog.String(attrEngine, m.engineName()), slog.String(attrError, err.Error()))
+	m.logger().LogAttrs(ctx, slog.LevelWarn, "Ignoring error in development mode", attrs...)
+}
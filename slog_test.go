@@ -0,0 +1,95 @@
+package golembic_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/dhermes/golembic"
+)
+
+// recordingLog implements `golembic.PrintfReceiver` and records each line.
+type recordingLog struct {
+	lines []string
+}
+
+func (rl *recordingLog) Printf(format string, a ...interface{}) (n int, err error) {
+	rl.lines = append(rl.lines, fmt.Sprintf(format, a...))
+	return 0, nil
+}
+
+func TestSlogPrecedence(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name string
+		// Set determines how the structured logger is set on the manager:
+		// via `OptManagerSlog()` ("option"), by assigning `Manager.Slog`
+		// ("field") or not at all ("").
+		Set string
+	}{
+		{Name: "printf only", Set: ""},
+		{Name: "option", Set: "option"},
+		{Name: "field", Set: "field"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations := newSequence(
+				t,
+				[]golembic.MigrationOption{golembic.OptRevision("a"), golembic.OptUpFromSQL("CREATE TABLE t1 (id INTEGER)")},
+				[]golembic.MigrationOption{golembic.OptRevision("b"), golembic.OptUpFromSQL("CREATE TABLE t2 (id INTEGER)")},
+			)
+			var buf bytes.Buffer
+			handler := slog.NewTextHandler(&buf, nil)
+			rl := &recordingLog{}
+			opts := []golembic.ManagerOption{golembic.OptManagerLog(rl)}
+			if tc.Set == "option" {
+				opts = append(opts, golembic.OptManagerSlog(handler))
+			}
+			m := newSQLiteManager(t, migrations, opts...)
+			if tc.Set == "field" {
+				m.Slog = slog.New(handler)
+			}
+
+			ctx := context.Background()
+			err := m.DownOne(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = m.Up(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = m.Describe(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			messages := []string{
+				"No migrations to roll back; no migrations have been run",
+				"Applying a: ",
+				"Applying b: ",
+				"0 | a | ",
+			}
+			structured := buf.String()
+			plain := strings.Join(rl.lines, "\n")
+			for _, message := range messages {
+				if tc.Set == "" && !strings.Contains(plain, message) {
+					t.Errorf("plain output missing %q:\n%s", message, plain)
+				}
+				if tc.Set != "" && !strings.Contains(structured, message) {
+					t.Errorf("structured output missing %q:\n%s", message, structured)
+				}
+			}
+			if tc.Set != "" && len(rl.lines) > 0 {
+				t.Errorf("unexpected plain output with a structured logger:\n%s", plain)
+			}
+		})
+	}
+}
@@ -4,9 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
+const (
+	// auditColumns are the (nullable) columns in the migrations metadata table
+	// that describe how a migration was applied, in order.
+	auditColumns = "checksum, applied_by, hostname, duration_ms, app_version"
+	// metadataColumns are the columns read from the migrations metadata table
+	// by `readAllMigration()`, in order.
+	metadataColumns = "revision, previous, created_at, merges, dirty, " + auditColumns
+)
+
 // NOTE: Ensure that
 //   - `timeColumnPointer` satisfies `TimestampColumn`.
 var (
@@ -39,23 +49,61 @@ func readAllInt(ctx context.Context, tx *sql.Tx, query string, args ...interface
 	return
 }
 
+// storedColumns holds the values of the columns in `metadataColumns` read
+// off of a `sql.Rows`.
+type storedColumns struct {
+	Revision   string
+	Previous   sql.NullString
+	Merges     sql.NullString
+	Dirty      bool
+	Checksum   sql.NullString
+	AppliedBy  sql.NullString
+	Hostname   sql.NullString
+	DurationMS sql.NullInt64
+	AppVersion sql.NullString
+}
+
 // migrationFromQuery is intended to be used to construct a metadata row
-// from a pair of values read off of a `sql.Rows`.
-func migrationFromQuery(previous sql.NullString, revision string, createdAt time.Time) Migration {
-	if previous.Valid {
-		return Migration{Previous: previous.String, Revision: revision, createdAt: createdAt}
+// from values read off of a `sql.Rows`.
+func migrationFromQuery(sc storedColumns, createdAt time.Time) Migration {
+	migration := Migration{
+		Revision:  sc.Revision,
+		createdAt: createdAt,
+		dirty:     sc.Dirty,
+		applyMetadata: ApplyMetadata{
+			Duration: time.Duration(sc.DurationMS.Int64) * time.Millisecond,
+		},
 	}
-
 	// Handle NULL.
-	return Migration{Revision: revision, createdAt: createdAt}
+	if sc.Previous.Valid {
+		migration.Previous = sc.Previous.String
+	}
+	if sc.Merges.Valid && sc.Merges.String != "" {
+		migration.Merges = strings.Split(sc.Merges.String, ",")
+	}
+	if sc.Checksum.Valid {
+		migration.Checksum = sc.Checksum.String
+	}
+	if sc.AppliedBy.Valid {
+		migration.applyMetadata.AppliedBy = sc.AppliedBy.String
+	}
+	if sc.Hostname.Valid {
+		migration.applyMetadata.Hostname = sc.Hostname.String
+	}
+	if sc.AppVersion.Valid {
+		migration.applyMetadata.ApplicationVersion = sc.AppVersion.String
+	}
+
+	return migration
 }
 
 // readAllMigration performs a SQL query and reads all rows into a
-// `Migration` slice, under the assumption that three columns -- revision,
-// previous and created_at -- are being returned for the query (in that order).
-// For example, the query
+// `Migration` slice, under the assumption that the columns in
+// `metadataColumns` -- revision, previous, created_at, merges, dirty,
+// checksum, applied_by, hostname, duration_ms and app_version -- are being
+// returned for the query (in that order). For example, the query
 //
-//	SELECT revision, previous, created_at FROM golembic_migrations
+//	SELECT revision, previous, created_at, merges, ... FROM golembic_migrations
 //
 // would satisfy this. A more "focused" query would return the latest migration
 // applied
@@ -63,7 +111,11 @@ func migrationFromQuery(previous sql.NullString, revision string, createdAt time
 //	SELECT
 //	  revision,
 //	  previous,
-//	  created_at
+//	  created_at,
+//	  merges,
+//	  dirty,
+//	  checksum,
+//	  ...
 //	FROM
 //	  golembic_migrations
 //	ORDER BY
@@ -80,19 +132,35 @@ func readAllMigration(ctx context.Context, tx *sql.Tx, query string, createdAt T
 		return
 	}
 
-	var revision string
-	var previous sql.NullString
 	for rows.Next() {
-		err = rows.Scan(&revision, &previous, createdAt.Pointer())
+		sc := storedColumns{}
+		err = rows.Scan(
+			&sc.Revision,
+			&sc.Previous,
+			createdAt.Pointer(),
+			&sc.Merges,
+			&sc.Dirty,
+			&sc.Checksum,
+			&sc.AppliedBy,
+			&sc.Hostname,
+			&sc.DurationMS,
+			&sc.AppVersion,
+		)
 		if err != nil {
 			return
 		}
-		result = append(result, migrationFromQuery(previous, revision, createdAt.Timestamp()))
+		result = append(result, migrationFromQuery(sc, createdAt.Timestamp()))
 	}
 
 	return
 }
 
+// nullString converts a string to a `sql.NullString`, where the empty string
+// is treated as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 // rowsClose is intended to be used in `defer` blocks to ensure that a SQL
 // query `Rows` iterator is always closed after being consumed (or abandonded
 // during iteration).
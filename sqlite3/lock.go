@@ -0,0 +1,149 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dhermes/golembic"
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.LockProvider`.
+//   - `SQLProvider` satisfies `golembic.StaleLockProvider`.
+var (
+	_ golembic.LockProvider      = (*SQLProvider)(nil)
+	_ golembic.StaleLockProvider = (*SQLProvider)(nil)
+)
+
+const (
+	// LockTable is the name of the table used to store locks. SQLite has no
+	// equivalent of advisory locks, so a lock is held by inserting a row
+	// into this table.
+	LockTable = "golembic_locks"
+	// LockPollInterval is the amount of time to wait between attempts to
+	// acquire a lock.
+	LockPollInterval = 100 * time.Millisecond
+
+	createLockTableSQL = `
+CREATE TABLE IF NOT EXISTS %s (
+  name        VARCHAR(255) NOT NULL PRIMARY KEY,
+  owner       VARCHAR(255),
+  acquired_at INTEGER DEFAULT (CAST((julianday('now') - 2440587.5) * 86400.0 * 1000000 AS INTEGER))
+)
+`
+)
+
+// AcquireLock acquires a named lock by inserting a row into `LockTable`,
+// polling every `LockPollInterval` until the row can be inserted or `timeout`
+// has elapsed; a `timeout` of zero means waiting indefinitely.
+//
+// The row records the owner (host and process ID) of the lock and when it was
+// acquired. If a process exits while holding a lock, the row is left behind;
+// once the owner is confirmed to no longer be running, the stale lock can be
+// released via `ForceReleaseLock()`.
+func (sp *SQLProvider) AcquireLock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error {
+	table := sp.QuoteIdentifier(LockTable)
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(createLockTableSQL, table))
+	if err != nil {
+		return err
+	}
+
+	statement := fmt.Sprintf("INSERT OR IGNORE INTO %s (name, owner) VALUES (?1, ?2)", table)
+	deadline := time.Now().Add(timeout)
+	for {
+		result, err := conn.ExecContext(ctx, statement, name, lockOwner())
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 1 {
+			return nil
+		}
+
+		if timeout > 0 && time.Now().After(deadline) {
+			return sp.lockHeldError(ctx, conn, name, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(LockPollInterval):
+		}
+	}
+}
+
+// ReleaseLock releases a named lock by deleting the row from `LockTable`.
+func (sp *SQLProvider) ReleaseLock(ctx context.Context, conn *sql.Conn, name string) error {
+	statement := fmt.Sprintf("DELETE FROM %s WHERE name = ?1", sp.QuoteIdentifier(LockTable))
+	result, err := conn.ExecContext(ctx, statement, name)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected != 1 {
+		return fmt.Errorf("%w; name: %q", golembic.ErrLockNotReleased, name)
+	}
+
+	return nil
+}
+
+// ForceReleaseLock releases a named lock by deleting the row from `LockTable`
+// regardless of which process acquired it. This is intended to be used to
+// release a stale lock, i.e. a lock held by a process that exited without
+// releasing it.
+func (sp *SQLProvider) ForceReleaseLock(ctx context.Context, conn *sql.Conn, name string) error {
+	err := sp.ReleaseLock(ctx, conn, name)
+	if errors.Is(err, golembic.ErrLockNotReleased) {
+		return fmt.Errorf("%w; the lock is not held", err)
+	}
+
+	return err
+}
+
+// lockHeldError returns `golembic.ErrLockNotAcquired` for a lock that could
+// not be acquired within `timeout`, describing which process holds the lock
+// (and since when) so that an operator can decide if the lock is stale.
+func (sp *SQLProvider) lockHeldError(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error {
+	err := fmt.Errorf("%w; name: %q, timeout: %s", golembic.ErrLockNotAcquired, name, timeout)
+
+	query := fmt.Sprintf("SELECT owner, acquired_at FROM %s WHERE name = ?1", sp.QuoteIdentifier(LockTable))
+	owner := sql.NullString{}
+	acquiredAt := TimeFromInteger{}
+	queryErr := conn.QueryRowContext(ctx, query, name).Scan(&owner, &acquiredAt)
+	if errors.Is(queryErr, sql.ErrNoRows) {
+		return err
+	}
+	if queryErr != nil {
+		return fmt.Errorf("%w; failed to read lock owner: %v", err, queryErr)
+	}
+
+	return fmt.Errorf(
+		"%w; held by %q since %s, if that process is no longer running release the stale lock via `repair --release-lock` (`ReleaseStaleLock()`)",
+		err, owner.String, acquiredAt.Timestamp().UTC().Format(time.RFC3339),
+	)
+}
+
+// lockOwner describes the current process, i.e. the host and process ID, so
+// that a stale lock can be attributed to the process that acquired it.
+func lockOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
@@ -0,0 +1,193 @@
+package sqlite3_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/dhermes/golembic"
+	"github.com/dhermes/golembic/sqlite3"
+)
+
+// newManager creates a manager with a single migration backed by the SQLite
+// database in `filename`. Managers created with the same `filename` act like
+// separate processes that share a database.
+func newManager(t *testing.T, filename string) *golembic.Manager {
+	t.Helper()
+
+	provider, err := sqlite3.New(
+		sqlite3.OptDataSourceName("file:"+filename),
+		sqlite3.OptDriverName("sqlite"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := golembic.NewMigration(
+		golembic.OptRevision("a"),
+		golembic.OptUpFromSQL("CREATE TABLE t1 (id INTEGER)"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := golembic.NewSequence(*root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := golembic.NewManager(
+		golembic.OptManagerProvider(provider),
+		golembic.OptManagerSequence(migrations),
+		golembic.OptManagerMigrationLockTimeout(250*time.Millisecond),
+		golembic.OptManagerLog(testLog{t: t}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		err := m.CloseConnectionPool()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	return m
+}
+
+// testLog implements `golembic.PrintfReceiver` and sends log lines to the
+// test log.
+type testLog struct {
+	t *testing.T
+}
+
+func (tl testLog) Printf(format string, a ...interface{}) (n int, err error) {
+	tl.t.Helper()
+	tl.t.Logf(format, a...)
+	return 0, nil
+}
+
+// lockRows returns the number of rows in the lock table.
+func lockRows(t *testing.T, m *golembic.Manager) int {
+	t.Helper()
+
+	ctx := context.Background()
+	pool, err := m.EnsureConnectionPool(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	err = pool.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", sqlite3.LockTable)).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestLockHeldByAnotherManager(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "golembic.db")
+	holder := newManager(t, filename)
+	waiter := newManager(t, filename)
+	ctx := context.Background()
+
+	release, err := holder.AcquireMigrationLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = waiter.Up(ctx)
+	if !errors.Is(err, golembic.ErrLockNotAcquired) {
+		t.Fatalf("error = %v, want %v", err, golembic.ErrLockNotAcquired)
+	}
+	if !strings.Contains(err.Error(), "held by") {
+		t.Fatalf("error = %v, want the owner of the lock", err)
+	}
+
+	err = release()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = waiter.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := lockRows(t, waiter); count != 0 {
+		t.Fatalf("lock rows = %d, want 0", count)
+	}
+}
+
+func TestReleaseLockAfterCancel(t *testing.T) {
+	t.Parallel()
+
+	m := newManager(t, filepath.Join(t.TempDir(), "golembic.db"))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	release, err := m.AcquireMigrationLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	err = release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := lockRows(t, m); count != 0 {
+		t.Fatalf("lock rows = %d, want 0", count)
+	}
+}
+
+func TestReleaseStaleLock(t *testing.T) {
+	t.Parallel()
+
+	m := newManager(t, filepath.Join(t.TempDir(), "golembic.db"))
+	ctx := context.Background()
+
+	// NOTE: Acquire the lock and drop the connection without releasing it,
+	//       i.e. the same as a process that exits while holding the lock.
+	pool, err := m.EnsureConnectionPool(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Provider.(golembic.LockProvider).AcquireLock(ctx, conn, m.MetadataTable, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up(ctx)
+	if !errors.Is(err, golembic.ErrLockNotAcquired) {
+		t.Fatalf("error = %v, want %v", err, golembic.ErrLockNotAcquired)
+	}
+
+	err = m.ReleaseStaleLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.ReleaseStaleLock(ctx)
+	if !errors.Is(err, golembic.ErrLockNotReleased) {
+		t.Fatalf("error = %v, want %v", err, golembic.ErrLockNotReleased)
+	}
+}
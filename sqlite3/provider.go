@@ -9,8 +9,14 @@ import (
 
 // NOTE: Ensure that
 //   - `SQLProvider` satisfies `golembic.EngineProvider`.
+//   - `SQLProvider` satisfies `golembic.TransactionalDDLProvider`.
+//   - `SQLProvider` satisfies `golembic.EngineNameProvider`.
+//   - `SQLProvider` satisfies `golembic.SchemaProvider`.
 var (
-	_ golembic.EngineProvider = (*SQLProvider)(nil)
+	_ golembic.EngineProvider           = (*SQLProvider)(nil)
+	_ golembic.TransactionalDDLProvider = (*SQLProvider)(nil)
+	_ golembic.EngineNameProvider       = (*SQLProvider)(nil)
+	_ golembic.SchemaProvider           = (*SQLProvider)(nil)
 )
 
 // New creates a SQLite-specific database engine provider from some
@@ -55,6 +61,17 @@ func (*SQLProvider) NewCreateTableParameters() golembic.CreateTableParameters {
 	return ctp
 }
 
+// EngineName returns the name of the database engine, i.e. "sqlite3".
+func (*SQLProvider) EngineName() string {
+	return "sqlite3"
+}
+
+// TransactionalDDL indicates that SQLite supports running DDL statements
+// (e.g. `CREATE TABLE`) inside a transaction.
+func (*SQLProvider) TransactionalDDL() bool {
+	return true
+}
+
 // TimestampColumn produces a value that can be used for reading / writing
 // an `INTEGER` column to a `time.Time` in SQLite.
 func (*SQLProvider) TimestampColumn() golembic.TimestampColumn {
@@ -88,3 +105,17 @@ func (sp *SQLProvider) Open() (*sql.DB, error) {
 func (sp *SQLProvider) TableExistsSQL() string {
 	return "SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?1;"
 }
+
+// ColumnExistsSQL returns a SQL query that can be used to determine if a
+// column exists in a table.
+//
+// See: https://www.sqlite.org/pragma.html#pragfunc
+func (sp *SQLProvider) ColumnExistsSQL() string {
+	return "SELECT 1 FROM pragma_table_info(?1) WHERE name = ?2;"
+}
+
+// IndexExistsSQL returns a SQL query that can be used to determine if an
+// index exists on a table.
+func (sp *SQLProvider) IndexExistsSQL() string {
+	return "SELECT 1 FROM sqlite_master WHERE type = 'index' AND tbl_name = ?1 AND name = ?2;"
+}
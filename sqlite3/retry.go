@@ -0,0 +1,48 @@
+package sqlite3
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dhermes/golembic"
+)
+
+const (
+	// sqliteBusy is the (primary) result code `SQLITE_BUSY`.
+	sqliteBusy = 5
+	// sqliteLocked is the (primary) result code `SQLITE_LOCKED`.
+	sqliteLocked = 6
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.RetryableErrorProvider`.
+var (
+	_ golembic.RetryableErrorProvider = (*SQLProvider)(nil)
+)
+
+// codeError is satisfied by the error types of some SQLite drivers, e.g.
+// `*sqlite.Error` from `modernc.org/sqlite`.
+type codeError interface {
+	Code() int
+}
+
+// IsRetryable indicates if `err` is `SQLITE_BUSY` or `SQLITE_LOCKED`
+// (including extended result codes). For drivers with an error type that does
+// not have a `Code()` method, the error message is matched instead.
+//
+// See: https://www.sqlite.org/rescode.html
+func (*SQLProvider) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var ce codeError
+	if errors.As(err, &ce) {
+		primary := ce.Code() & 0xff
+		return primary == sqliteBusy || primary == sqliteLocked
+	}
+
+	message := err.Error()
+	return strings.Contains(message, "database is locked") ||
+		strings.Contains(message, "database table is locked")
+}
@@ -0,0 +1,51 @@
+package sqlite3
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dhermes/golembic"
+)
+
+// NOTE: Ensure that
+//   - `SQLProvider` satisfies `golembic.TimeoutProvider`.
+var (
+	_ golembic.TimeoutProvider = (*SQLProvider)(nil)
+)
+
+// TimeoutSQL produces statements that override `busy_timeout` (the time spent
+// waiting on a locked database) for a single migration along with a statement
+// that restores the current value (e.g. from the DSN). SQLite does not have a
+// statement timeout, so a non-zero `statementTimeout` returns
+// `golembic.ErrTimeoutUnsupported` rather than being silently ignored.
+//
+// See: https://www.sqlite.org/pragma.html#pragma_busy_timeout
+func (*SQLProvider) TimeoutSQL(ctx context.Context, q golembic.RowQuerier, lockTimeout, statementTimeout time.Duration, _ bool) (set, reset []string, err error) {
+	if statementTimeout != 0 {
+		err = fmt.Errorf(
+			"%w; SQLite has no statement timeout, statement timeout: %s",
+			golembic.ErrTimeoutUnsupported, statementTimeout,
+		)
+		return
+	}
+
+	if lockTimeout == 0 {
+		return
+	}
+
+	ms, err := golembic.ToRoundDuration(lockTimeout, time.Millisecond)
+	if err != nil {
+		return
+	}
+
+	var current int64
+	err = q.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&current)
+	if err != nil {
+		return
+	}
+
+	set = []string{fmt.Sprintf("PRAGMA busy_timeout = %d", ms)}
+	reset = []string{fmt.Sprintf("PRAGMA busy_timeout = %d", current)}
+	return
+}
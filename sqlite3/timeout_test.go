@@ -0,0 +1,73 @@
+package sqlite3_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dhermes/golembic"
+	"github.com/dhermes/golembic/sqlite3"
+)
+
+func TestTimeoutSQL(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name             string
+		LockTimeout      time.Duration
+		StatementTimeout time.Duration
+		Set              []string
+		Reset            []string
+		Err              error
+	}{
+		{Name: "no timeouts"},
+		{
+			Name:        "lock timeout",
+			LockTimeout: 2500 * time.Millisecond,
+			Set:         []string{"PRAGMA busy_timeout = 2500"},
+			Reset:       []string{"PRAGMA busy_timeout = 1000"},
+		},
+		{Name: "lock timeout not in milliseconds", LockTimeout: 1500 * time.Microsecond, Err: golembic.ErrDurationConversion},
+		{Name: "statement timeout", StatementTimeout: time.Second, Err: golembic.ErrTimeoutUnsupported},
+		{
+			Name:             "both timeouts",
+			LockTimeout:      time.Second,
+			StatementTimeout: time.Second,
+			Err:              golembic.ErrTimeoutUnsupported,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			filename := filepath.Join(t.TempDir(), "golembic.db")
+			pool, err := sql.Open("sqlite", "file:"+filename+"?_pragma=busy_timeout(1000)")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				err := pool.Close()
+				if err != nil {
+					t.Error(err)
+				}
+			})
+
+			sp := &sqlite3.SQLProvider{}
+			set, reset, err := sp.TimeoutSQL(context.Background(), pool, tc.LockTimeout, tc.StatementTimeout, true)
+			if tc.Err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("error = %v, want %v", err, tc.Err)
+			}
+			if !reflect.DeepEqual(set, tc.Set) || !reflect.DeepEqual(reset, tc.Reset) {
+				t.Fatalf("TimeoutSQL() = %q, %q; want %q, %q", set, reset, tc.Set, tc.Reset)
+			}
+		})
+	}
+}
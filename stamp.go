@@ -0,0 +1,108 @@
+package golembic
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Stamp records `revision` and every ancestor of it that has not been applied
+// as applied **without** invoking `Up` or `UpConn`. This is intended for
+// adopting golembic on an existing database or for recording a migration that
+// was applied by hand. The stored history is verified against the sequence
+// and all rows are inserted in a single transaction while holding the
+// migration lock.
+//
+// Unlike `UpTo()`, this does not check milestones; it is assumed that the
+// schema changes have already been made.
+func (m *Manager) Stamp(ctx context.Context, revision string) error {
+	return m.withMigrationLock(ctx, func() error {
+		return m.stamp(ctx, revision)
+	})
+}
+
+// stamp is the unlocked form of `Stamp()`.
+func (m *Manager) stamp(ctx context.Context, revision string) (err error) {
+	err = m.EnsureMigrationsTable(ctx)
+	if err != nil {
+		return
+	}
+
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	history, _, err := m.verifyHistory(ctx, tx)
+	if err != nil {
+		return
+	}
+
+	err = m.checkDirty(ctx, tx)
+	if err != nil {
+		return
+	}
+
+	applied := []string{}
+	for _, row := range history {
+		applied = append(applied, row.Revision)
+	}
+
+	migrations, err := m.Sequence.PendingUntil(applied, revision)
+	if err != nil {
+		return
+	}
+
+	if len(migrations) == 0 {
+		m.printf("No migrations to stamp; revision %s has already been applied", revision)
+		return
+	}
+
+	err = m.insertStamped(ctx, tx, migrations)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// stampMigrations creates a transaction that stamps `migrations`, i.e.
+// inserts them into the migrations metadata table without running them.
+func (m *Manager) stampMigrations(ctx context.Context, migrations []Migration) (err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	err = m.insertStamped(ctx, tx, migrations)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// insertStamped inserts `migrations` into the migrations metadata table
+// (in order) without running them.
+func (m *Manager) insertStamped(ctx context.Context, tx *sql.Tx, migrations []Migration) error {
+	for _, migration := range migrations {
+		m.printf("Stamping %s: %s", migration.Revision, migration.ExtendedDescription())
+		err := m.InsertMigration(ctx, tx, migration)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package golembic_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dhermes/golembic"
+)
+
+func TestStamp(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name     string
+		Setup    func(context.Context, *golembic.Manager) error
+		Revision string
+		Err      error
+		// Want maps each revision in the metadata table to its `dirty` flag.
+		Want map[string]bool
+	}{
+		{
+			Name:     "fresh database",
+			Revision: "c",
+			Want:     map[string]bool{"a": false, "b": false, "c": false},
+		},
+		{
+			Name: "partially applied",
+			Setup: func(ctx context.Context, m *golembic.Manager) error {
+				return m.Stamp(ctx, "a")
+			},
+			Revision: "c",
+			Want:     map[string]bool{"a": false, "b": false, "c": false},
+		},
+		{
+			Name: "already applied",
+			Setup: func(ctx context.Context, m *golembic.Manager) error {
+				return m.Stamp(ctx, "c")
+			},
+			Revision: "b",
+			Want:     map[string]bool{"a": false, "b": false, "c": false},
+		},
+		{
+			Name:     "not registered",
+			Revision: "x",
+			Err:      golembic.ErrMigrationNotRegistered,
+			Want:     map[string]bool{},
+		},
+		{
+			Name: "dirty migration",
+			Setup: func(ctx context.Context, m *golembic.Manager) error {
+				err := m.Stamp(ctx, "a")
+				if err != nil {
+					return err
+				}
+
+				err = m.Up(ctx)
+				if !errors.Is(err, golembic.ErrMigrationDirty) {
+					return err
+				}
+				return nil
+			},
+			Revision: "c",
+			Err:      golembic.ErrMigrationDirty,
+			Want:     map[string]bool{"a": false, "b": true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			// NOTE: Every migration other than `d` fails if it is run, so
+			//       stamping must not run any of them.
+			migrations := newSequence(
+				t,
+				[]golembic.MigrationOption{golembic.OptRevision("a"), golembic.OptUpFromSQL("SELECT * FROM stamped")},
+				[]golembic.MigrationOption{
+					golembic.OptRevision("b"),
+					golembic.OptUpConn(func(ctx context.Context, conn *sql.Conn) error {
+						_, err := conn.ExecContext(ctx, "SELECT * FROM stamped")
+						return err
+					}),
+				},
+				[]golembic.MigrationOption{golembic.OptRevision("c"), golembic.OptUpFromSQL("SELECT * FROM stamped")},
+				[]golembic.MigrationOption{golembic.OptRevision("d"), golembic.OptUpFromSQL("CREATE TABLE t (id INTEGER)")},
+			)
+			m := newSQLiteManager(t, migrations)
+			ctx := context.Background()
+
+			if tc.Setup != nil {
+				err := tc.Setup(ctx, m)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := m.Stamp(ctx, tc.Revision)
+			if tc.Err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("error = %v, want %v", err, tc.Err)
+			}
+
+			got := appliedRows(t, m)
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("applied = %v, want %v", got, tc.Want)
+			}
+			if tc.Err != nil {
+				return
+			}
+
+			// The stamped migrations are not run by a later `Up()`.
+			err = m.Up(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
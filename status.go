@@ -0,0 +1,110 @@
+package golembic
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// StatusEntry describes a registered migration and whether or not it has
+// been applied.
+type StatusEntry struct {
+	Revision    string
+	Description string
+	Milestone   bool
+	Baseline    bool
+	// Transactional indicates if the migration runs inside a transaction,
+	// i.e. it uses `Up` rather than `UpConn`.
+	Transactional bool
+	// Applied indicates if the migration has been applied. If not, the
+	// migration is pending.
+	Applied bool
+	// Dirty indicates that the migration was started but did not complete,
+	// i.e. it may have been partially applied. A dirty migration is also
+	// `Applied` since it has a row in the migrations metadata table.
+	Dirty bool
+	// AppliedAt is the time when the migration was applied; it will be the
+	// zero value for a pending migration.
+	AppliedAt time.Time
+	// ApplyMetadata describes how the migration was applied; it will be the
+	// zero value for a pending migration.
+	ApplyMetadata ApplyMetadata
+}
+
+// ExtendedDescription is an extended form of `se.Description` that also
+// incorporates other information like whether the migration is a baseline or
+// a milestone.
+func (se StatusEntry) ExtendedDescription() string {
+	description := se.Description
+	if se.Baseline {
+		description += baselineSuffix
+	}
+	if se.Milestone {
+		description += milestoneSuffix
+	}
+
+	return description
+}
+
+// Status compares the rows in the migrations metadata table to the sequence
+// and returns an entry for every registered migration (in order) describing
+// whether it has been applied or is pending.
+func (m *Manager) Status(ctx context.Context) (entries []StatusEntry, err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	err = m.checkMigrationsTable(ctx)
+	if err != nil {
+		return
+	}
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	history, registered, err := m.verifyHistory(ctx, tx)
+	if err != nil {
+		return
+	}
+
+	stored := map[string]Migration{}
+	for _, row := range history {
+		stored[row.Revision] = row
+	}
+
+	for _, migration := range registered {
+		entry := StatusEntry{
+			Revision:      migration.Revision,
+			Description:   migration.Description,
+			Milestone:     migration.Milestone,
+			Baseline:      migration.Baseline,
+			Transactional: migration.Transactional(),
+		}
+		if row, ok := stored[migration.Revision]; ok {
+			entry.Applied = true
+			entry.Dirty = row.dirty
+			entry.AppliedAt = row.createdAt
+			entry.ApplyMetadata = row.applyMetadata
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return
+}
+
+// DirtyStatusError returns `ErrMigrationDirty` for the first dirty entry, if
+// any. This is intended for callers that use `Status()` rather than
+// `Verify()`.
+func DirtyStatusError(entries []StatusEntry) error {
+	for _, entry := range entries {
+		if entry.Dirty {
+			return dirtyError(entry.Revision)
+		}
+	}
+
+	return nil
+}
@@ -9,10 +9,17 @@ import (
 const (
 	createMigrationsTableSQL = `
 CREATE TABLE %s (
-  serial_id  %s,
-  revision   %s,
-  previous   %s,
-  created_at %s%s
+  serial_id   %s,
+  revision    %s,
+  previous    %s,
+  created_at  %s,
+  merges      %s,
+  dirty       %s,
+  checksum    %s,
+  applied_by  %s,
+  hostname    %s,
+  duration_ms %s,
+  app_version %s%s
 )
 `
 	pkMigrationsTableSQL = `
@@ -24,9 +31,9 @@ ALTER TABLE %[1]s
   ADD CONSTRAINT %[2]s FOREIGN KEY (previous)
   REFERENCES %[1]s(revision)
 `
-	uqPreviousMigrationsTableSQL = `
-ALTER TABLE %s
-  ADD CONSTRAINT %s UNIQUE (previous)
+	idxPreviousMigrationsTableSQL = `
+CREATE INDEX %s
+  ON %s (previous)
 `
 	uqSerialIDSQL = `
 ALTER TABLE %s
@@ -67,6 +74,13 @@ type CreateTableParameters struct {
 	Revision                 string
 	Previous                 string
 	CreatedAt                string
+	Merges                   string
+	Dirty                    string
+	Checksum                 string
+	AppliedBy                string
+	Hostname                 string
+	DurationMS               string
+	AppVersion               string
 	Constraints              string
 	SkipConstraintStatements bool
 }
@@ -82,6 +96,10 @@ func NewCreateTableParameters(opts ...CreateTableOption) CreateTableParameters {
 	ctp.ensureSerialID()
 	ctp.ensureRevision()
 	ctp.ensurePrevious()
+	ctp.ensureMerges()
+	ctp.ensureDirty()
+	ctp.ensureChecksum()
+	ctp.ensureApplyMetadata()
 	ctp.ensureConstraints()
 
 	return ctp
@@ -140,10 +158,73 @@ func (ctp *CreateTableParameters) ensurePrevious() {
 		return
 	}
 
-	ctp.Previous = "VARCHAR(32) UNIQUE CHECK (previous != revision)"
+	// NOTE: `previous` is not `UNIQUE` because two (or more) migrations on
+	//       different branches may share the same parent.
+	ctp.Previous = "VARCHAR(32) CHECK (previous != revision)"
+	return
+}
+
+// ensureMerges makes sure that `Merges` is set on the current
+// `CreateTableParameters` receiver.
+func (ctp *CreateTableParameters) ensureMerges() {
+	// Early exit if already set.
+	if ctp.Merges != "" {
+		return
+	}
+
+	// NOTE: The column stores a comma separated list of revisions and is
+	//       nullable because only merge migrations have additional parents.
+	ctp.Merges = "VARCHAR(255)"
+	return
+}
+
+// ensureDirty makes sure that `Dirty` is set on the current
+// `CreateTableParameters` receiver.
+func (ctp *CreateTableParameters) ensureDirty() {
+	// Early exit if already set.
+	if ctp.Dirty != "" {
+		return
+	}
+
+	// NOTE: The default ensures that rows inserted before the column was
+	//       added are not dirty.
+	ctp.Dirty = "BOOLEAN NOT NULL DEFAULT FALSE"
 	return
 }
 
+// ensureChecksum makes sure that `Checksum` is set on the current
+// `CreateTableParameters` receiver.
+func (ctp *CreateTableParameters) ensureChecksum() {
+	// Early exit if already set.
+	if ctp.Checksum != "" {
+		return
+	}
+
+	// NOTE: A SHA-256 digest is 64 characters when hex encoded. The column is
+	//       nullable because not every migration has a checksum.
+	ctp.Checksum = "VARCHAR(64)"
+	return
+}
+
+// ensureApplyMetadata makes sure that `AppliedBy`, `Hostname`, `DurationMS`
+// and `AppVersion` are set on the current `CreateTableParameters` receiver.
+// These columns are nullable because they are informational and may not be
+// known for every migration.
+func (ctp *CreateTableParameters) ensureApplyMetadata() {
+	if ctp.AppliedBy == "" {
+		ctp.AppliedBy = "VARCHAR(255)"
+	}
+	if ctp.Hostname == "" {
+		ctp.Hostname = "VARCHAR(255)"
+	}
+	if ctp.DurationMS == "" {
+		ctp.DurationMS = "BIGINT"
+	}
+	if ctp.AppVersion == "" {
+		ctp.AppVersion = "VARCHAR(255)"
+	}
+}
+
 // ensureConstraints makes sure that `Constraints` is set on the current
 // `CreateTableParameters` receiver.
 func (ctp *CreateTableParameters) ensureConstraints() {
@@ -163,9 +244,10 @@ func (ctp *CreateTableParameters) ensureConstraints() {
 }
 
 // CreateMigrationsTable invokes SQL statements required to create the metadata
-// table used to track migrations. If the table already exists (as detected by
-// `provider.TableExistsSQL()`), this function will not attempt to create a
-// table or any constraints.
+// table used to track migrations (along with the side table that stores the
+// version of the metadata table, at `LatestMetadataTableVersion`). If the
+// table already exists (as detected by `provider.TableExistsSQL()`), this
+// function will not attempt to create a table or any constraints.
 func CreateMigrationsTable(ctx context.Context, manager *Manager) (err error) {
 	var tx *sql.Tx
 	defer func() {
@@ -186,12 +268,17 @@ func CreateMigrationsTable(ctx context.Context, manager *Manager) (err error) {
 		return
 	}
 
-	ctp, createStatement := createMigrationsSQL(manager)
+	ctp, createStatement := createMigrationsSQL(manager, manager.MetadataTable)
 	_, err = tx.ExecContext(ctx, createStatement)
 	if err != nil {
 		return
 	}
 
+	err = setMetadataTableVersion(ctx, tx, manager, LatestMetadataTableVersion)
+	if err != nil {
+		return
+	}
+
 	if ctp.SkipConstraintStatements {
 		err = tx.Commit()
 		return
@@ -217,7 +304,7 @@ func CreateMigrationsTable(ctx context.Context, manager *Manager) (err error) {
 		return
 	}
 
-	_, err = tx.ExecContext(ctx, uqPreviousMigrationsSQL(manager))
+	_, err = tx.ExecContext(ctx, idxPreviousMigrationsSQL(manager))
 	if err != nil {
 		return
 	}
@@ -236,8 +323,7 @@ func CreateMigrationsTable(ctx context.Context, manager *Manager) (err error) {
 	return
 }
 
-func createMigrationsSQL(manager *Manager) (CreateTableParameters, string) {
-	table := manager.MetadataTable
+func createMigrationsSQL(manager *Manager, table string) (CreateTableParameters, string) {
 	provider := manager.Provider
 	ctp := provider.NewCreateTableParameters()
 
@@ -248,6 +334,13 @@ func createMigrationsSQL(manager *Manager) (CreateTableParameters, string) {
 		ctp.Revision,
 		ctp.Previous,
 		ctp.CreatedAt,
+		ctp.Merges,
+		ctp.Dirty,
+		ctp.Checksum,
+		ctp.AppliedBy,
+		ctp.Hostname,
+		ctp.DurationMS,
+		ctp.AppVersion,
 		ctp.Constraints,
 	)
 	return ctp, statement
@@ -301,15 +394,15 @@ func nonNegativeSerialID(manager *Manager) string {
 	)
 }
 
-func uqPreviousMigrationsSQL(manager *Manager) string {
+func idxPreviousMigrationsSQL(manager *Manager) string {
 	table := manager.MetadataTable
-	uqConstraint := fmt.Sprintf("uq_%s_previous", table)
+	index := idxPreviousName(manager)
 
 	provider := manager.Provider
 	return fmt.Sprintf(
-		uqPreviousMigrationsTableSQL,
+		idxPreviousMigrationsTableSQL,
+		provider.QuoteIdentifier(index),
 		provider.QuoteIdentifier(table),
-		uqConstraint,
 	)
 }
 
@@ -338,8 +431,12 @@ func singleRootMigrationsSQL(manager *Manager) string {
 }
 
 func tableExists(ctx context.Context, tx *sql.Tx, manager *Manager) (bool, error) {
+	return namedTableExists(ctx, tx, manager, manager.MetadataTable)
+}
+
+func namedTableExists(ctx context.Context, tx *sql.Tx, manager *Manager, table string) (bool, error) {
 	query := manager.Provider.TableExistsSQL()
-	rows, err := readAllInt(ctx, tx, query, manager.MetadataTable)
+	rows, err := readAllInt(ctx, tx, query, table)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,90 @@
+package golembic
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// execer is the subset of `*sql.Tx` and `*sql.Conn` used to read the current
+// timeouts and to run the statements that override (and restore) them.
+type execer interface {
+	RowQuerier
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// withTimeouts wraps the `Up` (or `UpConn`) function of a migration so that
+// the migration's lock and statement timeouts (if any) are set before it runs
+// and restored to their current values afterwards. The timeouts are restored
+// even in a transaction, so that they don't apply to a later migration in the
+// same transaction (see `OptApplySingleTransaction()`).
+func (m *Manager) withTimeouts(migration Migration) (Migration, error) {
+	if migration.LockTimeout == 0 && migration.StatementTimeout == 0 {
+		return migration, nil
+	}
+
+	tp, ok := m.Provider.(TimeoutProvider)
+	if !ok {
+		err := fmt.Errorf("%w; revision: %q", ErrTimeoutUnsupported, migration.Revision)
+		return Migration{}, err
+	}
+
+	lockTimeout := migration.LockTimeout
+	statementTimeout := migration.StatementTimeout
+	if up := migration.Up; up != nil {
+		migration.Up = func(ctx context.Context, tx *sql.Tx) error {
+			return runWithTimeouts(ctx, tx, tp, lockTimeout, statementTimeout, true, func() error {
+				return up(ctx, tx)
+			})
+		}
+	}
+	if upConn := migration.UpConn; upConn != nil {
+		migration.UpConn = func(ctx context.Context, conn *sql.Conn) error {
+			return runWithTimeouts(ctx, conn, tp, lockTimeout, statementTimeout, false, func() error {
+				return upConn(ctx, conn)
+			})
+		}
+	}
+
+	return migration, nil
+}
+
+// runWithTimeouts runs the statements that override the timeouts, invokes
+// `fn` and then runs the statements that restore the timeouts (even if `fn`
+// fails). If `fn` fails in a transaction and the overrides are scoped to the
+// transaction (see `ScopedTimeoutProvider`), the timeouts are not restored
+// since the transaction will be rolled back.
+func runWithTimeouts(ctx context.Context, e execer, tp TimeoutProvider, lockTimeout, statementTimeout time.Duration, transactional bool, fn func() error) (err error) {
+	set, reset, err := tp.TimeoutSQL(ctx, e, lockTimeout, statementTimeout, transactional)
+	if err != nil {
+		return
+	}
+
+	for _, statement := range set {
+		_, err = e.ExecContext(ctx, statement)
+		if err != nil {
+			return
+		}
+	}
+	defer func() {
+		if err != nil && transactional && transactionScoped(tp) {
+			return
+		}
+
+		for _, statement := range reset {
+			_, resetErr := e.ExecContext(ctx, statement)
+			err = maybeWrap(err, resetErr, "failed to reset timeout")
+		}
+	}()
+
+	err = fn()
+	return
+}
+
+// transactionScoped determines if the statements that override the timeouts
+// in a transaction are scoped to the transaction.
+func transactionScoped(tp TimeoutProvider) bool {
+	stp, ok := tp.(ScopedTimeoutProvider)
+	return ok && stp.TransactionScopedTimeouts()
+}
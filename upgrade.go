@@ -0,0 +1,462 @@
+package golembic
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const (
+	// LatestMetadataTableVersion is the version of the schema for the
+	// migrations metadata table created by `CreateMigrationsTable()`. The
+	// versions are
+	//   - 1: `serial_id`, `revision`, `previous` and `created_at` columns
+	//   - 2: adds the `checksum` column
+	//   - 3: adds the `applied_by`, `hostname`, `duration_ms` and
+	//        `app_version` columns
+	//   - 4: adds the `merges` column and drops the `UNIQUE` constraint on
+	//        the `previous` column (to allow branches)
+	//   - 5: adds the `dirty` column
+	LatestMetadataTableVersion = 5
+
+	createVersionTableSQL = `
+CREATE TABLE %s (
+  version INTEGER NOT NULL
+)
+`
+	addColumnSQL = `
+ALTER TABLE %s
+  ADD COLUMN %s %s
+`
+	dropConstraintSQL = `
+ALTER TABLE %s
+  DROP CONSTRAINT %s
+`
+	copyMigrationsTableSQL = `
+INSERT INTO %s (%s)
+  SELECT %s FROM %s
+`
+	renameTableSQL = `
+ALTER TABLE %s
+  RENAME TO %s
+`
+	// versionThreeColumns are the columns in version 3 of the migrations
+	// metadata table.
+	versionThreeColumns = "serial_id, revision, previous, created_at, " + auditColumns
+	// versionFourColumns are the columns in version 4 of the migrations
+	// metadata table.
+	versionFourColumns = "serial_id, revision, previous, created_at, merges, " + auditColumns
+)
+
+// metadataTableUpgrade describes the steps needed to upgrade the migrations
+// metadata table to `Version` from the previous version.
+type metadataTableUpgrade struct {
+	Version int
+	Steps   func(manager *Manager, ctp CreateTableParameters) []upgradeStep
+}
+
+// upgradeStep is a single statement in an upgrade of the migrations metadata
+// table. If `Applied` is set, it determines if the statement has already been
+// applied, e.g. by an upgrade that was interrupted part way through on an
+// engine where each DDL statement causes an implicit commit; in that case the
+// statement is skipped.
+type upgradeStep struct {
+	Statement string
+	Applied   func(ctx context.Context, tx *sql.Tx, manager *Manager) (bool, error)
+}
+
+var (
+	// metadataTableUpgrades are the upgrades for every version after the
+	// first, in order.
+	metadataTableUpgrades = []metadataTableUpgrade{
+		{
+			Version: 2,
+			Steps: func(manager *Manager, ctp CreateTableParameters) []upgradeStep {
+				return []upgradeStep{
+					addColumnStep(manager, "checksum", ctp.Checksum),
+				}
+			},
+		},
+		{
+			Version: 3,
+			Steps: func(manager *Manager, ctp CreateTableParameters) []upgradeStep {
+				return []upgradeStep{
+					addColumnStep(manager, "applied_by", ctp.AppliedBy),
+					addColumnStep(manager, "hostname", ctp.Hostname),
+					addColumnStep(manager, "duration_ms", ctp.DurationMS),
+					addColumnStep(manager, "app_version", ctp.AppVersion),
+				}
+			},
+		},
+		{
+			Version: 4,
+			Steps: func(manager *Manager, ctp CreateTableParameters) []upgradeStep {
+				// NOTE: When `ALTER TABLE ... ADD CONSTRAINT ...` statements
+				//       can't be used (e.g. in SQLite), the `UNIQUE` constraint
+				//       is inline in the `CREATE TABLE` statement so the table
+				//       must be rebuilt.
+				if ctp.SkipConstraintStatements {
+					return rebuildMigrationsTableSteps(manager, versionThreeColumns)
+				}
+
+				return []upgradeStep{
+					addColumnStep(manager, "merges", ctp.Merges),
+					{
+						Statement: idxPreviousMigrationsSQL(manager),
+						Applied:   indexExists(idxPreviousName(manager)),
+					},
+					{
+						Statement: dropUniquePreviousStatement(manager),
+						Applied:   indexDropped(uqPreviousName(manager)),
+					},
+				}
+			},
+		},
+		{
+			Version: 5,
+			Steps: func(manager *Manager, ctp CreateTableParameters) []upgradeStep {
+				// NOTE: When the table must be rebuilt (e.g. in SQLite), the
+				//       version 4 upgrade uses the current schema so it may
+				//       have already added the `dirty` column. Rebuilding
+				//       (rather than adding the column) works either way.
+				if ctp.SkipConstraintStatements {
+					return rebuildMigrationsTableSteps(manager, versionFourColumns)
+				}
+
+				return []upgradeStep{
+					addColumnStep(manager, "dirty", ctp.Dirty),
+				}
+			},
+		},
+	}
+)
+
+// MetadataTableVersion determines the version of the schema for the
+// migrations metadata table. The version is stored in a side table (see
+// `VersionTable()`); if the side table does not exist, the migrations metadata
+// table is assumed to have been created before versioning was introduced,
+// i.e. version 1.
+//
+// NOTE: This assumes, but does not check, that the migrations metadata table
+// exists.
+func (m *Manager) MetadataTableVersion(ctx context.Context) (version int, err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	version, err = metadataTableVersion(ctx, tx, m)
+	return
+}
+
+// VersionTable is the name of the side table that stores the version of the
+// schema for the migrations metadata table.
+func (m *Manager) VersionTable() string {
+	return m.MetadataTable + "_version"
+}
+
+// UpgradeMetadataTable applies the `ALTER TABLE` statements needed to upgrade
+// the migrations metadata table to `LatestMetadataTableVersion`. Each version
+// is upgraded in a separate transaction and the migration lock is held while
+// upgrading. If the table is already up to date, this does nothing.
+func (m *Manager) UpgradeMetadataTable(ctx context.Context) error {
+	return m.withMigrationLock(ctx, func() error {
+		err := CreateMigrationsTable(ctx, m)
+		if err != nil {
+			return err
+		}
+
+		return m.upgradeMetadataTable(ctx)
+	})
+}
+
+// upgradeMetadataTable is the unlocked form of `UpgradeMetadataTable()`.
+func (m *Manager) upgradeMetadataTable(ctx context.Context) error {
+	version, err := m.MetadataTableVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, upgrade := range metadataTableUpgrades {
+		if upgrade.Version <= version {
+			continue
+		}
+
+		m.printf(
+			"Upgrading metadata table %s to version %d",
+			m.MetadataTable, upgrade.Version,
+		)
+		err = m.applyMetadataTableUpgrade(ctx, upgrade)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMetadataTableUpgrade creates a transaction that runs the statements
+// for a single upgrade and stores the new version.
+func (m *Manager) applyMetadataTableUpgrade(ctx context.Context, upgrade metadataTableUpgrade) (err error) {
+	var tx *sql.Tx
+	defer func() {
+		err = txFinalize(tx, err)
+	}()
+
+	tx, err = m.NewTx(ctx)
+	if err != nil {
+		return
+	}
+
+	ctp := m.Provider.NewCreateTableParameters()
+	for _, step := range upgrade.Steps(m, ctp) {
+		applied := false
+		if step.Applied != nil {
+			applied, err = step.Applied(ctx, tx, m)
+			if err != nil {
+				return
+			}
+		}
+		if applied {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, step.Statement)
+		if err != nil {
+			return
+		}
+	}
+
+	err = setMetadataTableVersion(ctx, tx, m, upgrade.Version)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// checkMetadataTableVersion makes sure the migrations metadata table is up
+// to date. If it is not and `upgrade` is set, the table will be upgraded.
+func (m *Manager) checkMetadataTableVersion(ctx context.Context, upgrade bool) error {
+	version, err := m.MetadataTableVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version >= LatestMetadataTableVersion {
+		return nil
+	}
+
+	if !upgrade {
+		return outdatedError(version)
+	}
+
+	return m.upgradeMetadataTable(ctx)
+}
+
+// requireLatestMetadataTableVersion makes sure the migrations metadata table
+// is up to date (without upgrading it) before the columns in
+// `metadataColumns` are read via `tx`. This way, a table created by an older
+// version of golembic (e.g. without the `checksum` column) results in
+// `ErrMetadataTableOutdated` rather than an error about a missing column.
+func (m *Manager) requireLatestMetadataTableVersion(ctx context.Context, tx *sql.Tx) error {
+	version, err := metadataTableVersion(ctx, tx, m)
+	if err != nil {
+		return err
+	}
+
+	if version >= LatestMetadataTableVersion {
+		return nil
+	}
+
+	return outdatedError(version)
+}
+
+// outdatedError returns `ErrMetadataTableOutdated` for a migrations metadata
+// table at `version`, including a hint on how to upgrade it.
+func outdatedError(version int) error {
+	return fmt.Errorf(
+		"%w; version: %d, latest version: %d, upgrade it via `upgrade-metadata-table` (`UpgradeMetadataTable()`) "+
+			"or apply migrations with `--auto-upgrade-metadata-table` (`OptManagerAutoUpgradeMetadataTable(true)`)",
+		ErrMetadataTableOutdated, version, LatestMetadataTableVersion,
+	)
+}
+
+// metadataTableVersion reads the version of the schema for the migrations
+// metadata table from the side table.
+func metadataTableVersion(ctx context.Context, tx *sql.Tx, manager *Manager) (int, error) {
+	exists, err := namedTableExists(ctx, tx, manager, manager.VersionTable())
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 1, nil
+	}
+
+	query := fmt.Sprintf(
+		"SELECT version FROM %s",
+		manager.Provider.QuoteIdentifier(manager.VersionTable()),
+	)
+	rows, err := readAllInt(ctx, tx, query)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) != 1 {
+		err = fmt.Errorf(
+			"%w; expected exactly one row in %s, found %d",
+			ErrMetadataTableOutdated, manager.VersionTable(), len(rows),
+		)
+		return 0, err
+	}
+
+	return rows[0], nil
+}
+
+// setMetadataTableVersion stores the version of the schema for the migrations
+// metadata table in the side table, creating the side table if necessary.
+func setMetadataTableVersion(ctx context.Context, tx *sql.Tx, manager *Manager, version int) error {
+	table := manager.Provider.QuoteIdentifier(manager.VersionTable())
+	exists, err := namedTableExists(ctx, tx, manager, manager.VersionTable())
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(createVersionTableSQL, table))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", table))
+	if err != nil {
+		return err
+	}
+
+	statement := fmt.Sprintf(
+		"INSERT INTO %s (version) VALUES (%s)",
+		table,
+		manager.Provider.QueryParameter(1),
+	)
+	_, err = tx.ExecContext(ctx, statement, version)
+	return err
+}
+
+// addColumnStep adds a column to the migrations metadata table, unless the
+// column already exists.
+func addColumnStep(manager *Manager, column, columnType string) upgradeStep {
+	statement := fmt.Sprintf(
+		addColumnSQL,
+		manager.Provider.QuoteIdentifier(manager.MetadataTable),
+		column,
+		columnType,
+	)
+	return upgradeStep{Statement: statement, Applied: columnExists(column)}
+}
+
+// columnExists determines if a column exists in the migrations metadata
+// table. If the provider does not satisfy `SchemaProvider`, the column is
+// assumed to not exist.
+func columnExists(column string) func(context.Context, *sql.Tx, *Manager) (bool, error) {
+	return func(ctx context.Context, tx *sql.Tx, manager *Manager) (bool, error) {
+		sp, ok := manager.Provider.(SchemaProvider)
+		if !ok {
+			return false, nil
+		}
+
+		return rowExists(ctx, tx, sp.ColumnExistsSQL(), manager.MetadataTable, column)
+	}
+}
+
+// indexExists determines if an index (or a `UNIQUE` constraint) exists on the
+// migrations metadata table. If the provider does not satisfy
+// `SchemaProvider`, the index is assumed to not exist.
+func indexExists(index string) func(context.Context, *sql.Tx, *Manager) (bool, error) {
+	return func(ctx context.Context, tx *sql.Tx, manager *Manager) (bool, error) {
+		sp, ok := manager.Provider.(SchemaProvider)
+		if !ok {
+			return false, nil
+		}
+
+		return rowExists(ctx, tx, sp.IndexExistsSQL(), manager.MetadataTable, index)
+	}
+}
+
+// indexDropped determines if an index (or a `UNIQUE` constraint) has already
+// been dropped from the migrations metadata table. If the provider does not
+// satisfy `SchemaProvider`, the index is assumed to not have been dropped.
+func indexDropped(index string) func(context.Context, *sql.Tx, *Manager) (bool, error) {
+	return func(ctx context.Context, tx *sql.Tx, manager *Manager) (bool, error) {
+		if _, ok := manager.Provider.(SchemaProvider); !ok {
+			return false, nil
+		}
+
+		exists, err := indexExists(index)(ctx, tx, manager)
+		return !exists, err
+	}
+}
+
+// rowExists determines if `query` returns at least one row.
+func rowExists(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (bool, error) {
+	rows, err := readAllInt(ctx, tx, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return len(rows) > 0, nil
+}
+
+// idxPreviousName is the name of the index on the `previous` column of the
+// migrations metadata table.
+func idxPreviousName(manager *Manager) string {
+	return fmt.Sprintf("idx_%s_previous", manager.MetadataTable)
+}
+
+// uqPreviousName is the name of the `UNIQUE` constraint on the `previous`
+// column that was added by `CreateMigrationsTable()` prior to version 4.
+func uqPreviousName(manager *Manager) string {
+	return fmt.Sprintf("uq_%s_previous", manager.MetadataTable)
+}
+
+// dropUniquePreviousStatement drops the `UNIQUE` constraint on the `previous`
+// column that was added by `CreateMigrationsTable()` prior to version 4.
+func dropUniquePreviousStatement(manager *Manager) string {
+	table := manager.MetadataTable
+	constraint := uqPreviousName(manager)
+
+	if dcp, ok := manager.Provider.(DropConstraintProvider); ok {
+		return dcp.DropUniqueConstraintSQL(table, constraint)
+	}
+
+	return fmt.Sprintf(
+		dropConstraintSQL,
+		manager.Provider.QuoteIdentifier(table),
+		constraint,
+	)
+}
+
+// rebuildMigrationsTableSteps re-creates the migrations metadata table with
+// the current schema, copying over `columns` for all existing rows. These
+// steps are always applied since they are only used by engines with
+// transactional DDL (e.g. SQLite), where an upgrade can't be interrupted part
+// way through.
+func rebuildMigrationsTableSteps(manager *Manager, columns string) []upgradeStep {
+	provider := manager.Provider
+	table := provider.QuoteIdentifier(manager.MetadataTable)
+	rebuiltName := manager.MetadataTable + "_rebuilt"
+	rebuilt := provider.QuoteIdentifier(rebuiltName)
+
+	_, createStatement := createMigrationsSQL(manager, rebuiltName)
+	return []upgradeStep{
+		{Statement: createStatement},
+		{Statement: fmt.Sprintf(copyMigrationsTableSQL, rebuilt, columns, columns, table)},
+		{Statement: fmt.Sprintf("DROP TABLE %s", table)},
+		{Statement: fmt.Sprintf(renameTableSQL, rebuilt, table)},
+	}
+}
@@ -0,0 +1,141 @@
+package golembic_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dhermes/golembic"
+)
+
+const (
+	// versionOneTableSQL creates the migrations metadata table as it was
+	// before the schema was versioned, with the root migration applied.
+	versionOneTableSQL = `
+CREATE TABLE golembic_migrations (
+  serial_id  INTEGER NOT NULL,
+  revision   VARCHAR(32) NOT NULL PRIMARY KEY,
+  previous   VARCHAR(32) UNIQUE,
+  created_at INTEGER
+)
+`
+	versionOneRowSQL = "INSERT INTO golembic_migrations VALUES (0, 'a', NULL, 1)"
+)
+
+func TestUpgradeMetadataTable(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name string
+		// Setup are statements run before the manager is used.
+		Setup []string
+		// AutoUpgrade sets `OptManagerAutoUpgradeMetadataTable()`.
+		AutoUpgrade bool
+		// Version is the version of the metadata table before the upgrade.
+		Version int
+	}{
+		{
+			Name:    "fresh database",
+			Version: golembic.LatestMetadataTableVersion,
+		},
+		{
+			Name:    "version 1",
+			Setup:   []string{versionOneTableSQL, versionOneRowSQL},
+			Version: 1,
+		},
+		{
+			Name:        "version 1 with automatic upgrade",
+			Setup:       []string{versionOneTableSQL, versionOneRowSQL},
+			AutoUpgrade: true,
+			Version:     1,
+		},
+		{
+			// NOTE: Each DDL statement causes an implicit commit on some
+			//       engines, so an upgrade may be interrupted part way through
+			//       a version (i.e. before the version is stored).
+			Name: "interrupted upgrade",
+			Setup: []string{
+				versionOneTableSQL,
+				versionOneRowSQL,
+				"ALTER TABLE golembic_migrations ADD COLUMN checksum VARCHAR(64)",
+				"ALTER TABLE golembic_migrations ADD COLUMN applied_by VARCHAR(255)",
+			},
+			Version: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations := newSequence(
+				t,
+				[]golembic.MigrationOption{golembic.OptRevision("a"), golembic.OptUpFromSQL("CREATE TABLE t1 (id INTEGER)")},
+				[]golembic.MigrationOption{golembic.OptRevision("b"), golembic.OptUpFromSQL("CREATE TABLE t2 (id INTEGER)")},
+			)
+			m := newSQLiteManager(t, migrations, golembic.OptManagerAutoUpgradeMetadataTable(tc.AutoUpgrade))
+			ctx := context.Background()
+
+			pool, err := m.EnsureConnectionPool(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, statement := range tc.Setup {
+				_, err = pool.ExecContext(ctx, statement)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(tc.Setup) > 0 {
+				version, err := m.MetadataTableVersion(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if version != tc.Version {
+					t.Fatalf("version = %d, want %d", version, tc.Version)
+				}
+			}
+
+			if tc.Version < golembic.LatestMetadataTableVersion && !tc.AutoUpgrade {
+				err = m.Up(ctx)
+				if !errors.Is(err, golembic.ErrMetadataTableOutdated) {
+					t.Fatalf("error = %v, want %v", err, golembic.ErrMetadataTableOutdated)
+				}
+			}
+
+			// NOTE: Upgrading is idempotent, so the second upgrade is a no-op.
+			if !tc.AutoUpgrade {
+				for i := 0; i < 2; i++ {
+					err = m.UpgradeMetadataTable(ctx)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			err = m.Up(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			version, err := m.MetadataTableVersion(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != golembic.LatestMetadataTableVersion {
+				t.Fatalf("version = %d, want %d", version, golembic.LatestMetadataTableVersion)
+			}
+			err = m.Verify(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := appliedRows(t, m)
+			want := map[string]bool{"a": false, "b": false}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("applied = %v, want %v", got, want)
+			}
+		})
+	}
+}